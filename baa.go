@@ -1,12 +1,19 @@
 package baa
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"reflect"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -25,14 +32,35 @@ var Env string
 
 // Baa provlider an application
 type Baa struct {
-	debug           bool
-	name            string
-	di              DIer
-	router          Router
-	pool            sync.Pool
-	errorHandler    ErrorHandleFunc
-	notFoundHandler HandlerFunc
-	middleware      []HandlerFunc
+	debug            bool
+	name             string
+	di               DIer
+	router           Router
+	pool             sync.Pool
+	errorHandler     ErrorHandleFunc
+	panicHandler     ErrorHandleFunc
+	notFoundHandler  HandlerFunc
+	notAllowHandler  HandlerFunc
+	optionsHandler   HandlerFunc
+	notAcceptHandler HandlerFunc
+	middleware       []HandlerFunc
+	mounts           []*mount
+	hosts            []*host
+	mu               sync.Mutex
+	servers          []*http.Server
+	running          bool
+	startHooks       []HookFunc
+	shutdownHooks    []HookFunc
+	shutdownTimeout  time.Duration
+	validateMu       sync.RWMutex
+	validators       map[string]ValidateFunc
+	validateStructs  map[reflect.Type]*validateStruct
+	codecs           map[string]Codec
+	assets           []*asset
+	fingerprints     sync.Map
+	maxBodySize      int64
+	maxMemory        int64
+	stripBlankLines  bool
 }
 
 // Middleware middleware handler
@@ -44,16 +72,49 @@ type HandlerFunc func(*Context)
 // ErrorHandleFunc HTTP error handleFunc
 type ErrorHandleFunc func(error, *Context)
 
+// HookFunc application lifecycle hook func
+type HookFunc func(context.Context) error
+
+// mount is a sub application mounted under prefix
+type mount struct {
+	prefix string
+	app    *Baa
+}
+
+// host is a sub application serves requests of matched host
+type host struct {
+	pattern string
+	labels  []string
+	static  bool
+	app     *Baa
+}
+
+// mountParamsKey request context key of params passed to mounted handler
+type mountParamsKey struct{}
+
+// mountParams route params matched by parent application
+type mountParams struct {
+	names  []string
+	values []string
+}
+
 // appInstances storage application instances
 var appInstances map[string]*Baa
 
 // defaultAppName default application name
 const defaultAppName = "_default_"
 
+// defaultShutdownTimeout max time to wait for active requests when the
+// run context is done
+const defaultShutdownTimeout = 10 * time.Second
+
 // New create a baa application without any config.
 func New() *Baa {
 	b := new(Baa)
 	b.middleware = make([]HandlerFunc, 0)
+	b.shutdownTimeout = defaultShutdownTimeout
+	b.maxMemory = defaultMaxMemory
+	b.stripBlankLines = true
 	b.pool = sync.Pool{
 		New: func() interface{} {
 			return NewContext(nil, nil, b)
@@ -67,6 +128,12 @@ func New() *Baa {
 	b.SetDI("logger", log.New(os.Stderr, "[Baa] ", log.LstdFlags))
 	b.SetDI("render", newRender())
 	b.SetNotFound(b.DefaultNotFoundHandler)
+	b.SetMethodNotAllowed(b.DefaultMethodNotAllowedHandler)
+	b.codecs = make(map[string]Codec)
+	b.SetCodec(ApplicationJSON, JSONCodec{})
+	b.SetCodec(ApplicationXML, XMLCodec{})
+	b.SetCodec("text/xml", XMLCodec{})
+	b.SetCodec(ApplicationProtobuf, ProtobufCodec{})
 	return b
 }
 
@@ -113,38 +180,275 @@ func (b *Baa) RunTLSServer(s *http.Server, crtFile, keyFile string) {
 	b.run(s, crtFile, keyFile)
 }
 
+// RunWithContext runs a server until ctx is done, then shuts it down gracefully.
+func (b *Baa) RunWithContext(ctx context.Context, addr string) error {
+	return b.runContext(ctx, b.Server(addr))
+}
+
+// RunTLSWithContext runs a server with TLS configuration until ctx is done,
+// then shuts it down gracefully.
+func (b *Baa) RunTLSWithContext(ctx context.Context, addr, certfile, keyfile string) error {
+	return b.runContext(ctx, b.Server(addr), certfile, keyfile)
+}
+
+// RunServerWithContext runs a custom server until ctx is done,
+// then shuts it down gracefully.
+func (b *Baa) RunServerWithContext(ctx context.Context, s *http.Server) error {
+	return b.runContext(ctx, s)
+}
+
+// run runs a server and exits the process on error,
+// returns normally after a Shutdown.
 func (b *Baa) run(s *http.Server, files ...string) {
+	if err := b.serve(s, files...); err != nil {
+		b.Logger().Fatal(err)
+	}
+}
+
+// runContext runs a server and shuts it down when ctx is done,
+// it waits for active requests at most shutdownTimeout.
+func (b *Baa) runContext(ctx context.Context, s *http.Server, files ...string) error {
+	// the server is tracked before ctx is checked, so Shutdown always finds it
+	if err := b.prepare(s, files...); err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- b.listen(s, files...)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			b.stop(s)
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout)
+	defer cancel()
+	err := b.Shutdown(sctx)
+	if serr := <-errc; err == nil {
+		err = serr
+	}
+	return err
+}
+
+// serve fires start hooks then listens, returns nil when the server was shut down.
+func (b *Baa) serve(s *http.Server, files ...string) error {
+	if err := b.prepare(s, files...); err != nil {
+		return err
+	}
+	err := b.listen(s, files...)
+	if err != nil {
+		b.stop(s)
+	}
+	return err
+}
+
+// prepare checks TLS files, tracks the server and fires start hooks
+func (b *Baa) prepare(s *http.Server, files ...string) error {
+	if len(files) != 0 && len(files) != 2 {
+		panic("invalid TLS configuration")
+	}
 	s.Handler = b
+	return b.start(s)
+}
+
+// listen listens and serves, returns nil when the server was shut down.
+func (b *Baa) listen(s *http.Server, files ...string) error {
+	var err error
 	b.Logger().Printf("Run mode: %s", Env)
 	if len(files) == 0 {
 		b.Logger().Printf("Listen %s", s.Addr)
-		b.Logger().Fatal(s.ListenAndServe())
-	} else if len(files) == 2 {
-		b.Logger().Printf("Listen %s with TLS", s.Addr)
-		b.Logger().Fatal(s.ListenAndServeTLS(files[0], files[1]))
+		err = s.ListenAndServe()
 	} else {
-		panic("invalid TLS configuration")
+		b.Logger().Printf("Listen %s with TLS", s.Addr)
+		err = s.ListenAndServeTLS(files[0], files[1])
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// start tracks the server and fires start hooks for the first server
+func (b *Baa) start(s *http.Server) error {
+	b.mu.Lock()
+	first := !b.running
+	hooks := b.startHooks
+	b.running = true
+	b.mu.Unlock()
+
+	if first {
+		for _, h := range hooks {
+			if err := h(context.Background()); err != nil {
+				b.mu.Lock()
+				b.running = false
+				b.mu.Unlock()
+				return err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.servers = append(b.servers, s)
+	b.mu.Unlock()
+	return nil
+}
+
+// stop untracks the server failed to listen, shutdown hooks are fired
+// if it was the last running server, so resources of start hooks are released.
+func (b *Baa) stop(s *http.Server) {
+	b.mu.Lock()
+	for i := range b.servers {
+		if b.servers[i] == s {
+			b.servers = append(b.servers[:i:i], b.servers[i+1:]...)
+			break
+		}
+	}
+	last := b.running && len(b.servers) == 0
+	if last {
+		b.running = false
+	}
+	hooks := b.shutdownHooks
+	b.mu.Unlock()
+
+	if last {
+		ctx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout)
+		defer cancel()
+		b.fireShutdownHooks(ctx, hooks)
+	}
+}
+
+// Shutdown gracefully shuts down all running servers without interrupting
+// active requests, then fires shutdown hooks in reverse order of registration.
+// If ctx expires before the shutdown is complete, Shutdown returns the context's error,
+// the hooks are then called with a new context limited by the shutdown timeout.
+func (b *Baa) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	servers := b.servers
+	running := b.running
+	hooks := b.shutdownHooks
+	b.servers = nil
+	b.running = false
+	b.mu.Unlock()
+
+	var err error
+	for _, s := range servers {
+		if serr := s.Shutdown(ctx); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if !running {
+		return err
+	}
+	if ctx.Err() != nil {
+		// draining servers used up ctx, hooks still need time to release resources
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), b.shutdownTimeout)
+		defer cancel()
+	}
+	if herr := b.fireShutdownHooks(ctx, hooks); err == nil {
+		err = herr
+	}
+	return err
+}
+
+// fireShutdownHooks fires hooks in reverse order, returns the first error
+func (b *Baa) fireShutdownHooks(ctx context.Context, hooks []HookFunc) error {
+	var err error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if herr := hooks[i](ctx); herr != nil {
+			b.Logger().Printf("shutdown hook error: %v", herr)
+			if err == nil {
+				err = herr
+			}
+		}
 	}
+	return err
+}
+
+// OnStart registers a hook called before the first server starts listening,
+// an error aborts the start.
+func (b *Baa) OnStart(h HookFunc) {
+	b.mu.Lock()
+	b.startHooks = append(b.startHooks, h)
+	b.mu.Unlock()
+}
+
+// OnShutdown registers a hook called after servers have been shut down,
+// hooks are called in reverse order of registration.
+func (b *Baa) OnShutdown(h HookFunc) {
+	b.mu.Lock()
+	b.shutdownHooks = append(b.shutdownHooks, h)
+	b.mu.Unlock()
+}
+
+// SetShutdownTimeout sets the max time to wait for active requests
+// when the context of RunWithContext is done.
+func (b *Baa) SetShutdownTimeout(d time.Duration) {
+	b.shutdownTimeout = d
 }
 
 func (b *Baa) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// host routing
+	if len(b.hosts) > 0 {
+		if app, p := b.matchHost(r.Host); app != nil {
+			if p != nil {
+				r = r.WithContext(context.WithValue(r.Context(), mountParamsKey{}, p))
+			}
+			app.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	c := b.pool.Get().(*Context)
 	c.Reset(w, r)
 
+	// params from parent application
+	if p, ok := r.Context().Value(mountParamsKey{}).(*mountParams); ok {
+		c.pNames = append(c.pNames, p.names...)
+		c.pValues = append(c.pValues, p.values...)
+	}
+
+	if b.maxBodySize > 0 {
+		c.SetBodyLimit(b.maxBodySize)
+	}
+
 	// build handler chain
 	path := strings.Replace(r.URL.Path, "//", "/", -1)
 	h, name := b.Router().Match(r.Method, path, c)
 	c.routeName = name
 
-	// notFound
+	// notFound or methodNotAllowed
 	if h == nil {
-		c.handlers = append(c.handlers, b.notFoundHandler)
+		if methods := b.allowed(path); len(methods) > 0 {
+			c.Resp.Header().Set("Allow", strings.Join(methods, ", "))
+			c.handlers = append(c.handlers, b.notAllowHandler)
+		} else {
+			c.handlers = append(c.handlers, b.notFoundHandler)
+		}
 	} else {
 		c.handlers = append(c.handlers, h...)
 	}
 
+	defer b.release(c)
 	c.Next()
+}
 
+// release recovers panic in handlers then returns the context to pool
+func (b *Baa) release(c *Context) {
+	if v := recover(); v != nil {
+		if v == http.ErrAbortHandler {
+			c.release()
+			b.pool.Put(c)
+			panic(v)
+		}
+		b.Recover(&PanicError{Value: v, Stack: debug.Stack()}, c)
+	}
+	c.release()
 	b.pool.Put(c)
 }
 
@@ -183,11 +487,7 @@ func (b *Baa) Router() Router {
 
 // Use registers a middleware
 func (b *Baa) Use(m ...Middleware) {
-	for i := range m {
-		if m[i] != nil {
-			b.middleware = append(b.middleware, wrapMiddleware(m[i]))
-		}
-	}
+	b.middleware = append(b.middleware, wrapMiddlewares(m)...)
 }
 
 // SetDI registers a dependency injection
@@ -201,6 +501,9 @@ func (b *Baa) SetDI(name string, h interface{}) {
 		if _, ok := h.(Renderer); !ok {
 			panic("DI render must be implement interface baa.Renderer")
 		}
+		if r, ok := h.(*Render); ok {
+			r.setDefaults(b.FuncMap())
+		}
 	case "router":
 		if _, ok := h.(Router); !ok {
 			panic("DI router must be implement interface baa.Router")
@@ -223,24 +526,92 @@ func (b *Baa) Static(prefix string, dir string, index bool, h HandlerFunc) {
 	if dir == "" {
 		panic("baa.Static dir can not be empty")
 	}
-	b.Get(prefix+"*", newStatic(prefix, dir, index, h))
+	b.StaticWithOptions(prefix, StaticOptions{Dir: dir, Index: index, Handler: h})
+}
+
+// StaticFS set static file route serves files from fsys, such as embed.FS or os.DirFS,
+// file paths are relative to the root of fsys. index lists files of directory,
+// h is called before serving, it can be used for set headers.
+func (b *Baa) StaticFS(prefix string, fsys fs.FS, index bool, h HandlerFunc) {
+	if prefix == "" {
+		panic("baa.StaticFS prefix can not be empty")
+	}
+	if fsys == nil {
+		panic("baa.StaticFS fsys can not be nil")
+	}
+	b.StaticWithOptions(prefix, StaticOptions{FS: fsys, Index: index, Handler: h})
+}
+
+// StaticWithOptions set static file route with options
+func (b *Baa) StaticWithOptions(prefix string, opt StaticOptions) {
+	if prefix == "" {
+		panic("baa.StaticWithOptions prefix can not be empty")
+	}
+	if opt.Dir == "" && opt.FS == nil {
+		panic("baa.StaticWithOptions Dir or FS must be set")
+	}
+	fsys := opt.fs()
+	b.addAsset(prefix, fsys)
+	b.Get(prefix+"*", newStatic(prefix, fsys, opt))
 }
 
 // StaticFile shortcut for serve file
 func (b *Baa) StaticFile(pattern string, path string) RouteNode {
 	return b.Get(pattern, func(c *Context) {
-		if err := serveFile(path, c); err != nil {
+		c.File(path)
+	})
+}
+
+// StaticFileFS shortcut for serve file name in fsys
+func (b *Baa) StaticFileFS(pattern string, fsys fs.FS, name string) RouteNode {
+	return b.Get(pattern, func(c *Context) {
+		if err := serveFile(fsys, name, c); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.NotFound()
+				return
+			}
 			c.Error(err)
 		}
 	})
 }
 
+// SetStripBlankLines sets whether remove blank lines from rendered templates, default is true.
+// Blank lines in <pre> and <textarea> are removed too, disable it to keep them,
+// then Context.Render streams the output to response without buffering.
+func (b *Baa) SetStripBlankLines(v bool) {
+	b.stripBlankLines = v
+}
+
 // SetAutoHead sets the value who determines whether add HEAD method automatically
 // when GET method is added. Combo router will not be affected by this value.
 func (b *Baa) SetAutoHead(v bool) {
 	b.Router().SetAutoHead(v)
 }
 
+// SetAutoOptions sets the value who determines whether answer OPTIONS method
+// automatically with the methods registered for the requested route.
+// It has no effect if the router does not implement MethodRouter.
+func (b *Baa) SetAutoOptions(v bool) {
+	if r, ok := b.Router().(MethodRouter); ok {
+		r.SetAutoOptions(v)
+	}
+}
+
+// allowed returns the methods which have a route matched uri,
+// it is empty if the router does not implement MethodRouter.
+func (b *Baa) allowed(uri string) []string {
+	if r, ok := b.Router().(MethodRouter); ok {
+		return r.Allowed(uri)
+	}
+	return nil
+}
+
+// SetAutoOptionsHandler set a handler called before answer OPTIONS automatically,
+// it is used for add headers like CORS, the answer is skipped if the handler writes response.
+func (b *Baa) SetAutoOptionsHandler(h HandlerFunc) {
+	b.optionsHandler = h
+}
+
 // SetAutoTrailingSlash optional trailing slash.
 func (b *Baa) SetAutoTrailingSlash(v bool) {
 	b.Router().SetAutoTrailingSlash(v)
@@ -253,16 +624,8 @@ func (b *Baa) SetAutoTrailingSlash(v bool) {
 //	baa.Route("/", "GET,POST", h)
 func (b *Baa) Route(pattern, methods string, h ...HandlerFunc) RouteNode {
 	var ru RouteNode
-	var ms []string
-	if methods == "*" {
-		for m := range RouterMethods {
-			ms = append(ms, m)
-		}
-	} else {
-		ms = strings.Split(methods, ",")
-	}
-	for _, m := range ms {
-		ru = b.Router().Add(strings.TrimSpace(m), pattern, h)
+	for _, m := range splitMethods(methods) {
+		ru = b.Router().Add(m, pattern, h)
 	}
 	return ru
 }
@@ -272,6 +635,164 @@ func (b *Baa) Group(pattern string, f func(), h ...HandlerFunc) {
 	b.Router().GroupAdd(pattern, f, h)
 }
 
+// NewGroup create a route group has same prefix and handle chain,
+// middlewares are accepted as Use.
+func (b *Baa) NewGroup(pattern string, m ...Middleware) *Group {
+	return newRouteGroup(b, pattern, "", wrapMiddlewares(m))
+}
+
+// Mount mounts a sub application under prefix, the sub application
+// runs its own middleware, not found and error handlers with the prefix stripped,
+// params of prefix are available in the sub application.
+//
+// Example:
+//
+//	admin := baa.New()
+//	admin.Get("/users", h).Name("admin.users")
+//	app.Mount("/admin", admin)
+//	app.URLFor("admin.users") // "/admin/users"
+func (b *Baa) Mount(prefix string, app *Baa) {
+	if app == nil || app == b {
+		panic("baa.Mount app can not be nil or itself")
+	}
+	b.mounts = append(b.mounts, &mount{prefix: b.mount(prefix, app), app: app})
+}
+
+// MountHandler mounts a http.Handler under prefix, the handler serves
+// all requests begin with prefix, and sees the prefix stripped from URL path.
+func (b *Baa) MountHandler(prefix string, h http.Handler) {
+	b.mount(prefix, h)
+}
+
+// mount registers routes of prefix for the handler, returns the effective prefix
+func (b *Baa) mount(prefix string, h http.Handler) string {
+	if len(prefix) > 1 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	if prefix == "" || prefix == "/" || prefix[0] != '/' {
+		panic("baa.Mount prefix must begin with / and can not be root")
+	}
+	if strings.Contains(prefix, "*") {
+		panic("baa.Mount prefix can not contain wide route")
+	}
+	handler := func(c *Context) {
+		r := new(http.Request)
+		*r = *c.Req
+		r.URL = new(url.URL)
+		*r.URL = *c.Req.URL
+		r.URL.Path = "/" + c.Param("")
+		r.URL.RawPath = ""
+
+		p := new(mountParams)
+		for i := range c.pNames {
+			if c.pNames[i] != "" {
+				p.names = append(p.names, c.pNames[i])
+				p.values = append(p.values, c.pValues[i])
+			}
+		}
+		if len(p.names) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), mountParamsKey{}, p))
+		}
+		h.ServeHTTP(c.Resp, r)
+	}
+	// the mount matches every method, including extension methods like PROPFIND
+	rn := b.Router().Add(AnyMethod, prefix, []HandlerFunc{handler})
+	b.Router().Add(AnyMethod, prefix+"/*", []HandlerFunc{handler})
+	// the prefix of closure groups is added by the router
+	if n, ok := rn.(*Node); ok {
+		prefix = n.pattern
+	}
+	return prefix
+}
+
+// Host registers a sub application serves requests of the matched host,
+// requests of other hosts fallback to the routes of b.
+// A label of pattern can be a param like :tenant, which is available by c.Param,
+// and the first label can be * which matches one or more labels.
+//
+// Example:
+//
+//	app.Host("api.example.com", api)
+//	app.Host(":tenant.example.com", tenant)
+//	app.Host("*.example.com", others)
+func (b *Baa) Host(pattern string, app *Baa) {
+	if app == nil || app == b {
+		panic("baa.Host app can not be nil or itself")
+	}
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if pattern == "" {
+		panic("baa.Host pattern can not be empty")
+	}
+	h := &host{pattern: pattern, labels: strings.Split(pattern, "."), static: true, app: app}
+	for i, label := range h.labels {
+		if label == "" || label == ":" || (label == "*" && i > 0) {
+			panic("baa.Host pattern [" + pattern + "] is invalid")
+		}
+		if label[0] == ':' || label == "*" {
+			h.static = false
+		}
+	}
+	b.hosts = append(b.hosts, h)
+}
+
+// matchHost returns the application of matched host and params of host,
+// static hosts are matched before the others.
+func (b *Baa) matchHost(hostname string) (*Baa, *mountParams) {
+	if i := strings.LastIndexByte(hostname, ':'); i > strings.LastIndexByte(hostname, ']') {
+		hostname = hostname[:i]
+	}
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for _, h := range b.hosts {
+		if h.static && h.pattern == hostname {
+			return h.app, nil
+		}
+	}
+
+	labels := strings.Split(hostname, ".")
+	for _, h := range b.hosts {
+		if h.static {
+			continue
+		}
+		if p, ok := h.match(labels); ok {
+			return h.app, p
+		}
+	}
+	return nil, nil
+}
+
+// match check host labels, returns params of host
+func (h *host) match(labels []string) (*mountParams, bool) {
+	pattern := h.labels
+	if pattern[0] == "*" {
+		if len(labels) < len(pattern) {
+			return nil, false
+		}
+		pattern = pattern[1:]
+		labels = labels[len(labels)-len(pattern):]
+	} else if len(labels) != len(pattern) {
+		return nil, false
+	}
+
+	var p *mountParams
+	for i, label := range pattern {
+		if label[0] == ':' {
+			if labels[i] == "" {
+				return nil, false
+			}
+			if p == nil {
+				p = new(mountParams)
+			}
+			p.names = append(p.names, label[1:])
+			p.values = append(p.values, labels[i])
+			continue
+		}
+		if label != labels[i] {
+			return nil, false
+		}
+	}
+	return p, true
+}
+
 // Any is a shortcut for b.Router().handle("*", pattern, handlers)
 func (b *Baa) Any(pattern string, h ...HandlerFunc) RouteNode {
 	var ru RouteNode
@@ -351,6 +872,37 @@ func (b *Baa) NotFound(c *Context) {
 	http.NotFound(c.Resp, c.Req)
 }
 
+// SetMethodNotAllowed set method not allowed route handler,
+// the Allow header has been set when the handler is called.
+func (b *Baa) SetMethodNotAllowed(h HandlerFunc) {
+	b.notAllowHandler = h
+}
+
+// MethodNotAllowed execute method not allowed handler
+func (b *Baa) MethodNotAllowed(c *Context) {
+	if b.notAllowHandler != nil {
+		b.notAllowHandler(c)
+		return
+	}
+	b.DefaultMethodNotAllowedHandler(c)
+}
+
+// SetPanicHandler set the handler of panic recovered in handlers,
+// err is a *PanicError has the panic value and stack.
+func (b *Baa) SetPanicHandler(h ErrorHandleFunc) {
+	b.panicHandler = h
+}
+
+// Recover execute panic handler, logs the panic and invokes error handler by default
+func (b *Baa) Recover(err *PanicError, c *Context) {
+	if b.panicHandler != nil {
+		b.panicHandler(err, c)
+		return
+	}
+	b.Logger().Println(err)
+	b.Error(err, c)
+}
+
 // SetError set error handler
 func (b *Baa) SetError(h ErrorHandleFunc) {
 	b.errorHandler = h
@@ -365,13 +917,48 @@ func (b *Baa) Error(err error, c *Context) {
 		b.errorHandler(err, c)
 		return
 	}
-	code := http.StatusInternalServerError
-	msg := http.StatusText(code)
-	if b.debug {
-		msg = err.Error()
+	b.DefaultErrorHandler(err, c)
+}
+
+// DefaultErrorHandler invokes the default HTTP error handler,
+// it writes the code and message of HTTPError, other errors are treated as internal server error.
+// The response format is negotiated from the Accept header between JSON and plain text,
+// JSON is preferred when the error has details.
+func (b *Baa) DefaultErrorHandler(err error, c *Context) {
+	he := toHTTPError(err)
+	var pe *PanicError
+	if he.Code >= http.StatusInternalServerError && !errors.As(err, &pe) {
+		b.Logger().Println(err)
 	}
-	b.Logger().Println(err)
-	http.Error(c.Resp, msg, code)
+
+	offers := []string{TextPlain, ApplicationJSON, TextHTML}
+	if he.Details != nil {
+		offers[0], offers[1] = ApplicationJSON, TextPlain
+	}
+	if codec := b.Codec(ApplicationJSON); codec != nil && c.NegotiateFormat(offers...) == ApplicationJSON {
+		body := struct {
+			Code     int         `json:"code"`
+			Message  string      `json:"message"`
+			Details  interface{} `json:"details,omitempty"`
+			Internal string      `json:"internal,omitempty"`
+		}{Code: he.Code, Message: he.Message, Details: he.Details}
+		if b.debug && he.Internal != nil {
+			body.Internal = he.Internal.Error()
+		}
+		if re, jerr := b.marshal(codec, body); jerr == nil {
+			c.Resp.Header().Set("Content-Type", ApplicationJSONCharsetUTF8)
+			c.Resp.Header().Set("X-Content-Type-Options", "nosniff")
+			c.Resp.WriteHeader(he.Code)
+			c.Resp.Write(re)
+			return
+		}
+	}
+
+	msg := he.Message
+	if b.debug && he.Internal != nil {
+		msg += ": " + he.Internal.Error()
+	}
+	http.Error(c.Resp, msg, he.Code)
 }
 
 // DefaultNotFoundHandler invokes the default HTTP error handler.
@@ -381,9 +968,55 @@ func (b *Baa) DefaultNotFoundHandler(c *Context) {
 	http.Error(c.Resp, msg, code)
 }
 
-// URLFor use named route return format url
+// DefaultMethodNotAllowedHandler invokes the default HTTP method not allowed handler.
+func (b *Baa) DefaultMethodNotAllowedHandler(c *Context) {
+	code := http.StatusMethodNotAllowed
+	msg := http.StatusText(code)
+	http.Error(c.Resp, msg, code)
+}
+
+// URLFor use named route return format url,
+// named routes of mounted applications are resolved with the mount prefix.
 func (b *Baa) URLFor(name string, args ...interface{}) string {
-	return b.Router().URLFor(name, args...)
+	if u := b.Router().URLFor(name, args...); u != "" || name == "" {
+		return u
+	}
+	for _, m := range b.mounts {
+		format, n := formatPattern(m.prefix)
+		if n > len(args) {
+			continue
+		}
+		if u := m.app.URLFor(name, args[n:]...); u != "" {
+			return fmt.Sprintf(format, args[:n]...) + u
+		}
+	}
+	return ""
+}
+
+// splitMethods returns methods split by comma, * means all methods in RouterMethods
+func splitMethods(methods string) []string {
+	var ms []string
+	if methods == "*" {
+		for m := range RouterMethods {
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	for _, m := range strings.Split(methods, ",") {
+		ms = append(ms, strings.TrimSpace(m))
+	}
+	return ms
+}
+
+// wrapMiddlewares wraps middlewares to handlers, nil middlewares are skipped
+func wrapMiddlewares(m []Middleware) []HandlerFunc {
+	handlers := make([]HandlerFunc, 0, len(m))
+	for i := range m {
+		if m[i] != nil {
+			handlers = append(handlers, wrapMiddleware(m[i]))
+		}
+	}
+	return handlers
 }
 
 // wrapMiddleware wraps middleware.
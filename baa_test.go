@@ -1,11 +1,14 @@
 package baa
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -56,6 +59,147 @@ func TestRun(t *testing.T) {
 	})
 }
 
+func TestRunWithContext(t *testing.T) {
+	Convey("run baa app with context", t, func() {
+		Convey("shutdown drains active requests and fires hooks", func() {
+			b3 := New()
+			var hooks []string
+			b3.OnStart(func(ctx context.Context) error {
+				hooks = append(hooks, "start")
+				return nil
+			})
+			b3.OnShutdown(func(ctx context.Context) error {
+				hooks = append(hooks, "db")
+				return nil
+			})
+			b3.OnShutdown(func(ctx context.Context) error {
+				hooks = append(hooks, "queue")
+				return nil
+			})
+			started := make(chan struct{})
+			b3.Get("/slow", func(c *Context) {
+				close(started)
+				time.Sleep(100 * time.Millisecond)
+				c.String(200, "done")
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := make(chan error, 1)
+			go func() {
+				errc <- b3.RunWithContext(ctx, "127.0.0.1:8016")
+			}()
+
+			var resp *http.Response
+			var err error
+			done := make(chan struct{})
+			go func() {
+				for i := 0; i < 50; i++ {
+					resp, err = http.Get("http://127.0.0.1:8016/slow")
+					if err == nil {
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				close(done)
+			}()
+			<-started
+			cancel()
+			<-done
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			So(string(body), ShouldEqual, "done")
+			So(<-errc, ShouldBeNil)
+			So(hooks, ShouldResemble, []string{"start", "queue", "db"})
+		})
+		Convey("start hook error", func() {
+			b3 := New()
+			b3.OnStart(func(ctx context.Context) error {
+				return fmt.Errorf("db down")
+			})
+			err := b3.RunWithContext(context.Background(), "127.0.0.1:8017")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("listen error", func() {
+			b3 := New()
+			var starts, shutdowns int
+			b3.OnStart(func(ctx context.Context) error {
+				starts++
+				return nil
+			})
+			b3.OnShutdown(func(ctx context.Context) error {
+				shutdowns++
+				return nil
+			})
+			So(b3.RunWithContext(context.Background(), "127.0.0.1:-1"), ShouldNotBeNil)
+			So(b3.running, ShouldBeFalse)
+			So(b3.servers, ShouldBeEmpty)
+			So(b3.RunWithContext(context.Background(), "127.0.0.1:-1"), ShouldNotBeNil)
+			So(starts, ShouldEqual, 2)
+			So(shutdowns, ShouldEqual, 2)
+		})
+		Convey("shutdown without servers", func() {
+			b3 := New()
+			So(b3.Shutdown(context.Background()), ShouldBeNil)
+		})
+		Convey("shutdown timeout", func() {
+			b3 := New()
+			b3.SetShutdownTimeout(50 * time.Millisecond)
+			var hookErr error
+			b3.OnShutdown(func(ctx context.Context) error {
+				hookErr = ctx.Err()
+				return nil
+			})
+			started := make(chan struct{})
+			release := make(chan struct{})
+			b3.Get("/slow", func(c *Context) {
+				close(started)
+				<-release
+			})
+			defer close(release)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := make(chan error, 1)
+			go func() {
+				errc <- b3.RunWithContext(ctx, "127.0.0.1:8018")
+			}()
+			go func() {
+				for i := 0; i < 50; i++ {
+					if resp, err := http.Get("http://127.0.0.1:8018/slow"); err == nil {
+						resp.Body.Close()
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+			<-started
+			cancel()
+			So(errors.Is(<-errc, context.DeadlineExceeded), ShouldBeTrue)
+			// hooks have their own budget after draining timed out
+			So(hookErr, ShouldBeNil)
+		})
+		Convey("context done before serving", func() {
+			b3 := New()
+			var hooks []string
+			b3.OnStart(func(ctx context.Context) error {
+				hooks = append(hooks, "start")
+				return nil
+			})
+			b3.OnShutdown(func(ctx context.Context) error {
+				hooks = append(hooks, "shutdown")
+				return nil
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			So(b3.RunWithContext(ctx, "127.0.0.1:8019"), ShouldBeNil)
+			So(hooks, ShouldResemble, []string{"start", "shutdown"})
+			So(b3.running, ShouldBeFalse)
+			So(b3.servers, ShouldBeEmpty)
+		})
+	})
+}
+
 func TestServeHTTP(t *testing.T) {
 	Convey("ServeHTTP", t, func() {
 		Convey("normal serve", func() {
@@ -72,6 +216,46 @@ func TestServeHTTP(t *testing.T) {
 			w := request("GET", "/notfound2")
 			So(w.Code, ShouldEqual, http.StatusNotFound)
 		})
+		Convey("method not allowed serve", func() {
+			b.Get("/notallowed", func(c *Context) {
+				c.String(200, "ok")
+			})
+			b.Put("/notallowed", func(c *Context) {
+				c.String(200, "ok")
+			})
+			w := request("POST", "/notallowed")
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Header().Get("Allow"), ShouldEqual, "GET, PUT")
+
+			w = request("FOO", "/notallowed")
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+
+			w = request("POST", "/notallowed2")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Allow"), ShouldEqual, "")
+
+			b2 := New()
+			b2.Get("/notallowed", f)
+			b2.SetMethodNotAllowed(func(c *Context) {
+				c.String(405, "baa method not allowed: "+c.Resp.Header().Get("Allow"))
+			})
+			req, _ := http.NewRequest("DELETE", "/notallowed", nil)
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Body.String(), ShouldEqual, "baa method not allowed: GET")
+		})
+		Convey("router without MethodRouter", func() {
+			b2 := New()
+			b2.SetDI("router", struct{ Router }{NewTree(b2)})
+			b2.Get("/ok", f)
+			b2.SetAutoOptions(true)
+			req, _ := http.NewRequest("POST", "/ok", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Allow"), ShouldEqual, "")
+		})
 		Convey("error serve", func() {
 			b.SetError(func(err error, c *Context) {
 				c.Resp.WriteHeader(500)
@@ -107,6 +291,46 @@ func TestServeHTTP(t *testing.T) {
 			b2.ServeHTTP(w, req)
 			So(w.Code, ShouldEqual, http.StatusInternalServerError)
 		})
+		Convey("panic recovery", func() {
+			b2 := New()
+			b2.Get("/panic", func(c *Context) {
+				c.Set("dirty", true)
+				panic("BOMB")
+			})
+			b2.Get("/ok", func(c *Context) {
+				So(c.Get("dirty"), ShouldBeNil)
+				c.String(200, "ok")
+			})
+			req, _ := http.NewRequest("GET", "/panic", nil)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldContainSubstring, "panic: BOMB")
+
+			req, _ = http.NewRequest("GET", "/ok", nil)
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			var stack []byte
+			b2.SetPanicHandler(func(err error, c *Context) {
+				pe := err.(*PanicError)
+				stack = pe.Stack
+				c.String(503, fmt.Sprint(pe.Value))
+			})
+			req, _ = http.NewRequest("GET", "/panic", nil)
+			w = httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+			So(w.Body.String(), ShouldEqual, "BOMB")
+			So(string(stack), ShouldContainSubstring, "goroutine")
+
+			b2.Get("/abort", func(c *Context) {
+				panic(http.ErrAbortHandler)
+			})
+			req, _ = http.NewRequest("GET", "/abort", nil)
+			So(func() { b2.ServeHTTP(httptest.NewRecorder(), req) }, ShouldPanicWith, http.ErrAbortHandler)
+		})
 		Convey("Middleware", func() {
 			b2 := New()
 			b2.Use(func(c *Context) {
@@ -169,9 +393,158 @@ func TestServeHTTP(t *testing.T) {
 	})
 }
 
+func TestMount(t *testing.T) {
+	Convey("mount sub application", t, func() {
+		b2 := New()
+		admin := New()
+		admin.Use(func(c *Context) {
+			c.Resp.Header().Set("X-Admin", "true")
+			c.Next()
+		})
+		admin.Get("/", func(c *Context) {
+			c.String(200, "admin index")
+		}).Name("admin.index")
+		admin.Get("/users/:id", func(c *Context) {
+			c.String(200, c.Req.URL.Path+" "+c.Param("tenant")+" "+c.Param("id"))
+		}).Name("admin.user")
+		admin.SetNotFound(func(c *Context) {
+			c.String(404, "admin not found")
+		})
+		b2.Mount("/t/:tenant/admin/", admin)
+		b2.MountHandler("/debug", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("debug " + r.URL.Path))
+		}))
+
+		req, _ := http.NewRequest("GET", "/t/baa/admin/users/12", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("X-Admin"), ShouldEqual, "true")
+		So(w.Body.String(), ShouldEqual, "/users/12 baa 12")
+
+		req, _ = http.NewRequest("GET", "/t/baa/admin", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "admin index")
+
+		req, _ = http.NewRequest("GET", "/t/baa/admin/xxx", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, "admin not found")
+
+		req, _ = http.NewRequest("GET", "/debug/pprof/", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "debug /pprof/")
+
+		admin.Route("/files", "PROPFIND", func(c *Context) {
+			c.String(207, "propfind")
+		})
+		b2.Get("/debug/vars", func(c *Context) {
+			c.String(200, "vars")
+		})
+		req, _ = http.NewRequest("PROPFIND", "/t/baa/admin/files", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, 207)
+		So(w.Body.String(), ShouldEqual, "propfind")
+
+		req, _ = http.NewRequest("PURGE", "/debug/cache", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "debug /cache")
+
+		req, _ = http.NewRequest("GET", "/debug/vars", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "vars")
+
+		So(b2.URLFor("admin.user", "baa", 12), ShouldEqual, "/t/baa/admin/users/12")
+		So(b2.URLFor("admin.index", "baa"), ShouldEqual, "/t/baa/admin/")
+		So(b2.URLFor("admin.none", "baa"), ShouldEqual, "")
+
+		// the prefix of closure group is recorded
+		shop := New()
+		shop.Get("/items/:id", func(c *Context) {
+			c.String(200, c.Param("v")+" "+c.Param("id"))
+		}).Name("shop.item")
+		b2.Group("/api/:v", func() {
+			b2.Mount("/shop", shop)
+		})
+		So(serveRequest(b2, "GET", "/api/v1/shop/items/3").Body.String(), ShouldEqual, "v1 3")
+		So(b2.URLFor("shop.item", "v1", 3), ShouldEqual, "/api/v1/shop/items/3")
+
+		Convey("mount with invalid prefix", func() {
+			defer func() {
+				e := recover()
+				So(e, ShouldNotBeNil)
+			}()
+			b2.Mount("/", New())
+		})
+	})
+}
+
+func TestHost(t *testing.T) {
+	Convey("host routing", t, func() {
+		b2 := New()
+		b2.Get("/", func(c *Context) {
+			c.String(200, "default")
+		})
+		api := New()
+		api.Get("/", func(c *Context) {
+			c.String(200, "api")
+		})
+		tenant := New()
+		tenant.Get("/:page", func(c *Context) {
+			c.String(200, "tenant "+c.Param("tenant")+" "+c.Param("page"))
+		})
+		others := New()
+		others.Get("/", func(c *Context) {
+			c.String(200, "others")
+		})
+		b2.Host("api.example.com", api)
+		b2.Host(":tenant.example.com", tenant)
+		b2.Host("*.baa.example.com", others)
+
+		serve := func(host, uri string) string {
+			req, _ := http.NewRequest("GET", uri, nil)
+			req.Host = host
+			return serveHTTP(b2, req).Body.String()
+		}
+		So(serve("api.example.com", "/"), ShouldEqual, "api")
+		So(serve("API.example.com:8080", "/"), ShouldEqual, "api")
+		So(serve("foo.example.com", "/home"), ShouldEqual, "tenant foo home")
+		So(serve("a.b.baa.example.com", "/"), ShouldEqual, "others")
+		So(serve("example.com", "/"), ShouldEqual, "default")
+		So(serve("a.b.example.com", "/"), ShouldEqual, "default")
+
+		Convey("host with invalid pattern", func() {
+			defer func() {
+				e := recover()
+				So(e, ShouldNotBeNil)
+			}()
+			b2.Host("a.*.example.com", New())
+		})
+	})
+}
+
 func request(method, uri string) *httptest.ResponseRecorder {
+	return serveRequest(b, method, uri)
+}
+
+// serveRequest serves a request without body by app, header is the key value pairs set to the request
+func serveRequest(app *Baa, method, uri string, header ...string) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, uri, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return serveHTTP(app, req)
+}
+
+// serveHTTP serves req by app
+func serveHTTP(app *Baa, req *http.Request) *httptest.ResponseRecorder {
 	w := httptest.NewRecorder()
-	b.ServeHTTP(w, req)
+	app.ServeHTTP(w, req)
 	return w
 }
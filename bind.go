@@ -0,0 +1,325 @@
+package baa
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Binder is the interface implemented by types that bind themselves from request,
+// Context.Bind uses it instead of reflection, eg: binders generated by code.
+type Binder interface {
+	Bind(c *Context) error
+}
+
+// BindError is the error of a field failed to bind
+type BindError struct {
+	Field  string // key in request, eg: page
+	Source string // param, query, form, header, cookie or body
+	Value  string
+	Err    error
+}
+
+// BindErrors is a list of field errors, it is treated as 400 Bad Request by the default error handler
+type BindErrors []*BindError
+
+// bindSources tags of struct field in binding order
+var bindSources = []string{"param", "query", "form", "header", "cookie"}
+
+// bindField is a field of struct can be bound from request
+type bindField struct {
+	index      []int
+	tags       [2][]string // source, key in request
+	timeFormat string
+}
+
+// bindFieldsCache cache of bind fields by struct type
+var bindFieldsCache sync.Map
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Error returns the field error message
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("bind %s error: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("bind %s [%s] error: %v", e.Source, e.Field, e.Err)
+}
+
+// Unwrap returns the internal error
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the field error for client
+func (e *BindError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"field":   e.Field,
+		"source":  e.Source,
+		"message": e.Err.Error(),
+	})
+}
+
+// Error returns all of the field errors message
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Bind binds request data into dst which must be a pointer to struct.
+// Request body is decoded by Content-Type (JSON, XML or form) first,
+// then fields are filled by tags:
+//
+//	param:"id" query:"page" form:"name" header:"X-Token" cookie:"sid"
+//
+// field type can be string, bool, number, time.Time (tag time_format, default RFC3339),
+// time.Duration, encoding.TextUnmarshaler or slice of them.
+// If dst implements Binder, its Bind method is used instead.
+// Fields are validated by Context.Validate after binding.
+// A body of Content-Type without registered codec returns ErrUnsupportedMediaType.
+func (c *Context) Bind(dst interface{}) error {
+	if v, ok := dst.(Binder); ok {
+		if err := v.Bind(c); err != nil {
+			return err
+		}
+		return c.Validate(dst)
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("baa.Bind dst must be a pointer to struct")
+	}
+
+	if err := c.bindBody(dst); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrUnsupportedMediaType) {
+			return err
+		}
+		return BindErrors{&BindError{Source: "body", Err: err}}
+	}
+
+	var errs BindErrors
+	var query url.Values
+	rv = rv.Elem()
+	for _, f := range getBindFields(rv.Type()) {
+		for i, source := range f.tags[0] {
+			vals, ok := c.bindValues(source, f.tags[1][i], &query)
+			if !ok {
+				continue
+			}
+			if err := setField(rv.FieldByIndex(f.index), vals, f.timeFormat); err != nil {
+				errs = append(errs, &BindError{Field: f.tags[1][i], Source: source, Value: strings.Join(vals, ","), Err: err})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return c.Validate(dst)
+}
+
+// bindBody decodes request body by the codec of Content-Type, form bodies are parsed into form values
+func (c *Context) bindBody(dst interface{}) error {
+	if c.Req.Body == nil || c.Req.ContentLength == 0 {
+		return nil
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, ApplicationForm) || strings.HasPrefix(contentType, MultipartForm) {
+		return c.ParseForm(0)
+	}
+	content, err := c.Body().Bytes()
+	if err != nil || len(content) == 0 {
+		return err
+	}
+	codec := c.baa.Codec(contentType)
+	if codec == nil {
+		return ErrUnsupportedMediaType
+	}
+	return codec.Unmarshal(content, dst)
+}
+
+// bindValues returns values of key from source, ok is false when key not exists
+func (c *Context) bindValues(source, key string, query *url.Values) ([]string, bool) {
+	switch source {
+	case "param":
+		for i := len(c.pNames) - 1; i >= 0; i-- {
+			if c.pNames[i] == key {
+				return []string{c.pValues[i]}, true
+			}
+		}
+	case "query":
+		if *query == nil {
+			*query = c.Req.URL.Query()
+		}
+		vals, ok := (*query)[key]
+		return vals, ok
+	case "form":
+		if c.Req.PostForm == nil {
+			return nil, false
+		}
+		vals, ok := c.Req.PostForm[key]
+		return vals, ok
+	case "header":
+		vals, ok := c.Req.Header[textproto.CanonicalMIMEHeaderKey(key)]
+		return vals, ok
+	case "cookie":
+		if _, err := c.Req.Cookie(key); err == nil {
+			return []string{c.GetCookie(key)}, true
+		}
+	}
+	return nil, false
+}
+
+// getBindFields returns fields of struct type has bind tags, nested structs are included
+func getBindFields(t reflect.Type) []bindField {
+	if v, ok := bindFieldsCache.Load(t); ok {
+		return v.([]bindField)
+	}
+	fields := parseBindFields(t, nil)
+	bindFieldsCache.Store(t, fields)
+	return fields
+}
+
+// parseBindFields parses bind fields of struct type
+func parseBindFields(t reflect.Type, index []int) []bindField {
+	var fields []bindField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		f := bindField{timeFormat: sf.Tag.Get("time_format")}
+		f.index = make([]int, len(index)+1)
+		copy(f.index, index)
+		f.index[len(index)] = i
+		for _, source := range bindSources {
+			if key := sf.Tag.Get(source); key != "" && key != "-" {
+				f.tags[0] = append(f.tags[0], source)
+				f.tags[1] = append(f.tags[1], key)
+			}
+		}
+		if len(f.tags[0]) > 0 {
+			fields = append(fields, f)
+			continue
+		}
+		if sf.Type.Kind() == reflect.Struct && sf.Type != timeType &&
+			!reflect.PtrTo(sf.Type).Implements(textUnmarshalerType) {
+			fields = append(fields, parseBindFields(sf.Type, f.index)...)
+		}
+	}
+	return fields
+}
+
+// setField sets values to field
+func setField(v reflect.Value, vals []string, timeFormat string) error {
+	if len(vals) == 0 {
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i := range vals {
+			if err := setValue(s.Index(i), vals[i], timeFormat); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, vals[0], timeFormat)
+}
+
+// setValue converts string to the type of v then sets it
+func setValue(v reflect.Value, s string, timeFormat string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s, timeFormat)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Type() {
+	case timeType:
+		if s == "" {
+			return nil
+		}
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		t, err := time.Parse(timeFormat, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package baa
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type bindPage struct {
+	Page int `query:"page"`
+	Size int `query:"size"`
+}
+
+type bindUser struct {
+	bindPage
+	ID       int64         `param:"id" json:"-"`
+	Name     string        `json:"name" form:"name"`
+	Tags     []string      `query:"tag"`
+	Token    string        `header:"X-Token"`
+	Session  string        `cookie:"sid"`
+	Birthday time.Time     `query:"birthday" time_format:"2006-01-02"`
+	Timeout  time.Duration `query:"timeout"`
+	Score    *float64      `query:"score"`
+	Active   bool          `json:"active"`
+}
+
+type bindSelf struct {
+	ID string
+}
+
+func (v *bindSelf) Bind(c *Context) error {
+	v.ID = c.Param("id")
+	return nil
+}
+
+func TestContextBind(t *testing.T) {
+	Convey("context bind", t, func() {
+		b2 := New()
+		var user bindUser
+		var bindErr error
+		b2.Route("/bind/:id", "GET,POST", func(c *Context) {
+			user = bindUser{}
+			bindErr = c.Bind(&user)
+		})
+
+		Convey("bind json body with params, query, header and cookie", func() {
+			req, _ := http.NewRequest("POST", "/bind/12?page=2&tag=a&tag=b&birthday=2016-02-27&timeout=3s&score=9.5",
+				strings.NewReader(`{"name":"baa","active":true}`))
+			req.Header.Set("Content-Type", ApplicationJSONCharsetUTF8)
+			req.Header.Set("X-Token", "secret")
+			req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+			serveHTTP(b2, req)
+			So(bindErr, ShouldBeNil)
+			So(user.ID, ShouldEqual, 12)
+			So(user.Name, ShouldEqual, "baa")
+			So(user.Active, ShouldBeTrue)
+			So(user.Page, ShouldEqual, 2)
+			So(user.Tags, ShouldResemble, []string{"a", "b"})
+			So(user.Token, ShouldEqual, "secret")
+			So(user.Session, ShouldEqual, "s1")
+			So(user.Birthday.Format("2006-01-02"), ShouldEqual, "2016-02-27")
+			So(user.Timeout, ShouldEqual, 3*time.Second)
+			So(*user.Score, ShouldEqual, 9.5)
+		})
+
+		Convey("bind form body", func() {
+			data := url.Values{}
+			data.Set("name", "baa")
+			req, _ := http.NewRequest("POST", "/bind/1", strings.NewReader(data.Encode()))
+			req.Header.Set("Content-Type", ApplicationForm)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldBeNil)
+			So(user.Name, ShouldEqual, "baa")
+		})
+
+		Convey("bind field errors", func() {
+			req, _ := http.NewRequest("GET", "/bind/abc?page=x", nil)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldNotBeNil)
+			var errs BindErrors
+			So(errors.As(bindErr, &errs), ShouldBeTrue)
+			So(len(errs), ShouldEqual, 2)
+			So(errs[0].Field, ShouldEqual, "page")
+			So(errs[1].Field, ShouldEqual, "id")
+			So(errs[1].Source, ShouldEqual, "param")
+			So(toHTTPError(bindErr).Code, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("bind invalid body", func() {
+			req, _ := http.NewRequest("POST", "/bind/1", bytes.NewBufferString("{"))
+			req.Header.Set("Content-Type", ApplicationJSON)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldNotBeNil)
+			So(bindErr.(BindErrors)[0].Source, ShouldEqual, "body")
+		})
+
+		Convey("bind body of unsupported media type", func() {
+			req, _ := http.NewRequest("POST", "/bind/1", bytes.NewBufferString(`{"name":"baa"}`))
+			req.Header.Set("Content-Type", TextPlain)
+			So(serveHTTP(b2, req).Code, ShouldEqual, http.StatusOK)
+			So(errors.Is(bindErr, ErrUnsupportedMediaType), ShouldBeTrue)
+
+			req, _ = http.NewRequest("POST", "/bind/1", bytes.NewBufferString(`{"name":"baa"}`))
+			serveHTTP(b2, req)
+			So(errors.Is(bindErr, ErrUnsupportedMediaType), ShouldBeTrue)
+
+			req, _ = http.NewRequest("POST", "/bind/1", nil)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldBeNil)
+		})
+
+		Convey("bind with binder", func() {
+			var v bindSelf
+			b2.Get("/binder/:id", func(c *Context) {
+				bindErr = c.Bind(&v)
+			})
+			req, _ := http.NewRequest("GET", "/binder/x1", nil)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldBeNil)
+			So(v.ID, ShouldEqual, "x1")
+		})
+
+		Convey("bind invalid dst", func() {
+			var v int
+			b2.Get("/bindint", func(c *Context) {
+				bindErr = c.Bind(&v)
+			})
+			req, _ := http.NewRequest("GET", "/bindint", nil)
+			serveHTTP(b2, req)
+			So(bindErr, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package baa
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedMediaType is returned when no codec is registered for the request content type,
+// it is treated as 415 Unsupported Media Type by the default error handler.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Codec marshals and unmarshals values of a media type
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// IndentCodec is a codec can marshal indented output, it is used in debug mode
+type IndentCodec interface {
+	Codec
+	MarshalIndent(v interface{}, prefix, indent string) ([]byte, error)
+}
+
+// JSONCodec is the default codec of application/json based on encoding/json
+type JSONCodec struct{}
+
+// Marshal returns the JSON encoding of v
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// MarshalIndent returns the indented JSON encoding of v
+func (JSONCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+// Unmarshal parses the JSON data and stores the result in v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// XMLCodec is the default codec of application/xml based on encoding/xml
+type XMLCodec struct{}
+
+// Marshal returns the XML encoding of v
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+// MarshalIndent returns the indented XML encoding of v
+func (XMLCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return xml.MarshalIndent(v, prefix, indent)
+}
+
+// Unmarshal parses the XML data and stores the result in v
+func (XMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// ProtobufCodec is the default codec of application/protobuf,
+// it works with generated messages which have Marshal and Unmarshal methods.
+type ProtobufCodec struct{}
+
+// Marshal returns the protobuf encoding of v
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(interface {
+		Marshal() ([]byte, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("baa: %T is not a protobuf message", v)
+	}
+	return m.Marshal()
+}
+
+// Unmarshal parses the protobuf data and stores the result in v
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(interface {
+		Unmarshal([]byte) error
+	})
+	if !ok {
+		return fmt.Errorf("baa: %T is not a protobuf message", v)
+	}
+	return m.Unmarshal(data)
+}
+
+// SetCodec register codec for media type, it replaces the codec registered before.
+// Codecs should be registered before serving.
+func (b *Baa) SetCodec(mediaType string, codec Codec) {
+	typ, subtype := splitMediaType(mediaType)
+	if typ == "" || subtype == "" {
+		panic("baa.SetCodec invalid media type: " + mediaType)
+	}
+	if codec == nil {
+		panic("baa.SetCodec codec cannot be nil")
+	}
+	b.codecs[typ+"/"+subtype] = codec
+}
+
+// Codec returns codec registered for media type, params of media type are ignored
+func (b *Baa) Codec(mediaType string) Codec {
+	typ, subtype := splitMediaType(mediaType)
+	return b.codecs[typ+"/"+subtype]
+}
+
+// marshal encodes v by codec, indented in debug mode
+func (b *Baa) marshal(codec Codec, v interface{}) ([]byte, error) {
+	if ic, ok := codec.(IndentCodec); ok && b.debug {
+		return ic.MarshalIndent(v, "", "  ")
+	}
+	return codec.Marshal(v)
+}
+
+// Encode sends v encoded by the codec of media type with status code
+func (c *Context) Encode(code int, mediaType string, v interface{}) {
+	codec := c.baa.Codec(mediaType)
+	if codec == nil {
+		c.Error(fmt.Errorf("baa: no codec registered for %s", mediaType))
+		return
+	}
+	re, err := c.baa.marshal(codec, v)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Resp.Header().Set("Content-Type", mediaType)
+	c.Resp.WriteHeader(code)
+	c.Resp.Write(re)
+}
+
+// Decode decodes http.Request.Body by the codec of request Content-Type
+func (c *Context) Decode(v interface{}) error {
+	codec := c.baa.Codec(c.Req.Header.Get("Content-Type"))
+	if codec == nil {
+		return ErrUnsupportedMediaType
+	}
+	content, err := c.Body().Bytes()
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(content, v)
+}
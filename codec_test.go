@@ -0,0 +1,120 @@
+package baa
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testProtoMessage struct {
+	Name string
+}
+
+func (m *testProtoMessage) Marshal() ([]byte, error) {
+	return []byte(m.Name), nil
+}
+
+func (m *testProtoMessage) Unmarshal(data []byte) error {
+	m.Name = string(data)
+	return nil
+}
+
+type testUpperCodec struct{}
+
+func (testUpperCodec) Marshal(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(strings.ToUpper(s)), nil
+}
+
+func (testUpperCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = strings.ToLower(string(data))
+	return nil
+}
+
+func TestCodec1(t *testing.T) {
+	Convey("codec registry", t, func() {
+		b := New()
+		So(b.Codec(ApplicationJSON), ShouldHaveSameTypeAs, JSONCodec{})
+		So(b.Codec(ApplicationJSONCharsetUTF8), ShouldHaveSameTypeAs, JSONCodec{})
+		So(b.Codec("TEXT/XML"), ShouldHaveSameTypeAs, XMLCodec{})
+		So(b.Codec(ApplicationProtobuf), ShouldHaveSameTypeAs, ProtobufCodec{})
+		So(b.Codec("application/msgpack"), ShouldBeNil)
+
+		So(func() { b.SetCodec("upper", testUpperCodec{}) }, ShouldPanic)
+		So(func() { b.SetCodec("text/upper", nil) }, ShouldPanic)
+		b.SetCodec("text/upper", testUpperCodec{})
+		So(b.Codec("text/upper"), ShouldHaveSameTypeAs, testUpperCodec{})
+	})
+
+	Convey("encode and decode by codec", t, func() {
+		b := New()
+		b.SetCodec("text/upper", testUpperCodec{})
+		b.Post("/", func(c *Context) {
+			var s string
+			if err := c.Decode(&s); err != nil {
+				c.Error(err)
+				return
+			}
+			c.Encode(200, "text/upper", s+" baa")
+		})
+		b.Post("/proto", func(c *Context) {
+			m := new(testProtoMessage)
+			if err := c.Decode(m); err != nil {
+				c.Error(err)
+				return
+			}
+			c.Encode(200, ApplicationProtobuf, m)
+		})
+
+		req, _ := http.NewRequest("POST", "/", bytes.NewBufferString("Hello"))
+		req.Header.Set("Content-Type", "text/upper")
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Type"), ShouldEqual, "text/upper")
+		So(w.Body.String(), ShouldEqual, "HELLO BAA")
+
+		req, _ = http.NewRequest("POST", "/proto", bytes.NewBufferString("msg"))
+		req.Header.Set("Content-Type", ApplicationProtobuf)
+		w = httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "msg")
+
+		req, _ = http.NewRequest("POST", "/", bytes.NewBufferString("Hello"))
+		req.Header.Set("Content-Type", "application/msgpack")
+		w = httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+	})
+
+	Convey("replace json codec", t, func() {
+		b := New()
+		b.SetCodec(ApplicationJSON, testUpperCodec{})
+		b.Get("/", func(c *Context) {
+			c.JSON(200, "baa")
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		b.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "BAA")
+
+		// the error handler encodes by the registered codec, it can not encode the error body
+		b.Get("/error", func(c *Context) {
+			c.Error(ErrUnsupportedMediaType)
+		})
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/error", nil)
+		req.Header.Set("Accept", ApplicationJSON)
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+		So(w.Header().Get("Content-Type"), ShouldStartWith, TextPlain)
+	})
+}
@@ -1,19 +1,19 @@
 package baa
 
 import (
-	"bufio"
 	"bytes"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,7 +30,7 @@ var (
 
 const (
 	// defaultMaxMemory Maximum amount of memory to use when parsing a multipart form.
-	// Set this to whatever value you prefer; default is 32 MB.
+	// It can be changed by Baa.SetMaxMemory; default is 32 MB.
 	defaultMaxMemory = 32 << 20 // 32 MB
 
 	// CharsetUTF8 ...
@@ -50,6 +50,8 @@ const (
 	TextPlain                        = "text/plain"
 	TextPlainCharsetUTF8             = TextPlain + "; " + CharsetUTF8
 	MultipartForm                    = "multipart/form-data"
+	TextEventStream                  = "text/event-stream"
+	ApplicationNDJSON                = "application/x-ndjson"
 )
 
 // Context provlider a HTTP context for baa
@@ -65,6 +67,9 @@ type Context struct {
 	pValues    []string      // route params values
 	handlers   []HandlerFunc // middleware handler and route match handler
 	hi         int           // handlers execute position
+	rawBody    io.ReadCloser // request body before limited
+	body       []byte        // cached request body
+	releases   []func()      // called before the context is reused
 }
 
 // NewContext create a http context
@@ -94,6 +99,9 @@ func (c *Context) Reset(w http.ResponseWriter, r *http.Request) {
 	c.routeName = ""
 	c.pNames = c.pNames[:0]
 	c.pValues = c.pValues[:0]
+	c.rawBody = nil
+	c.body = nil
+	c.releases = c.releases[:0]
 	c.storeMutex.Lock()
 	c.store = nil
 	c.storeMutex.Unlock()
@@ -290,7 +298,7 @@ func (c *Context) QueryJSON(v interface{}) error {
 	if len(content) == 0 {
 		return ErrJSONPayloadEmpty
 	}
-	return json.Unmarshal(content, v)
+	return c.baa.Codec(ApplicationJSON).Unmarshal(content, v)
 }
 
 // QueryXML decode xml from http.Request.Body
@@ -302,7 +310,7 @@ func (c *Context) QueryXML(v interface{}) error {
 	if len(content) == 0 {
 		return ErrXMLPayloadEmpty
 	}
-	return xml.Unmarshal(content, v)
+	return c.baa.Codec(ApplicationXML).Unmarshal(content, v)
 }
 
 // GetFile returns information about user upload file by given form field name.
@@ -331,11 +339,42 @@ func (c *Context) SaveToFile(name, savePath string) error {
 	return err
 }
 
-// Body get raw request body and return RequestBody
+// Body get raw request body and return RequestBody,
+// the body is rewound and can be read again if it is cached by CacheBody.
 func (c *Context) Body() *RequestBody {
+	c.rewindBody()
 	return NewRequestBody(c.Req.Body)
 }
 
+// rewindBody resets request body to the start of cached body
+func (c *Context) rewindBody() {
+	if c.body != nil {
+		c.Req.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	}
+}
+
+// CacheBody reads request body into memory, then Body, QueryJSON, QueryXML and Posts
+// can read the same payload many times. It returns ErrBodyTooLarge if body is larger than max bytes.
+func (c *Context) CacheBody(max int64) error {
+	if c.body != nil || c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return nil
+	}
+	if c.Req.ContentLength > max {
+		return ErrBodyTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(c.Req.Body, max+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > max {
+		return ErrBodyTooLarge
+	}
+	c.Req.Body.Close()
+	c.body = body
+	c.rewindBody()
+	return nil
+}
+
 // SetCookie sets given cookie value to response header.
 // full params example:
 // SetCookie(<name>, <value>, <max age>, <path>, <domain>, <secure>, <http only>)
@@ -443,13 +482,7 @@ func (c *Context) Text(code int, s []byte) {
 
 // JSON write data by json format
 func (c *Context) JSON(code int, v interface{}) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = json.MarshalIndent(v, "", "  ")
-	} else {
-		re, err = json.Marshal(v)
-	}
+	re, err := c.baa.marshal(c.baa.Codec(ApplicationJSON), v)
 	if err != nil {
 		c.Error(err)
 		return
@@ -462,13 +495,7 @@ func (c *Context) JSON(code int, v interface{}) {
 
 // JSONString return string by Marshal interface
 func (c *Context) JSONString(v interface{}) (string, error) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = json.MarshalIndent(v, "", "  ")
-	} else {
-		re, err = json.Marshal(v)
-	}
+	re, err := c.baa.marshal(c.baa.Codec(ApplicationJSON), v)
 	if err != nil {
 		return "", err
 	}
@@ -477,7 +504,7 @@ func (c *Context) JSONString(v interface{}) (string, error) {
 
 // JSONP write data by jsonp format
 func (c *Context) JSONP(code int, callback string, v interface{}) {
-	re, err := json.Marshal(v)
+	re, err := c.baa.Codec(ApplicationJSON).Marshal(v)
 	if err != nil {
 		c.Error(err)
 		return
@@ -492,13 +519,7 @@ func (c *Context) JSONP(code int, callback string, v interface{}) {
 
 // XML sends an XML response with status code.
 func (c *Context) XML(code int, v interface{}) {
-	var re []byte
-	var err error
-	if c.baa.debug {
-		re, err = xml.MarshalIndent(v, "", "  ")
-	} else {
-		re, err = xml.Marshal(v)
-	}
+	re, err := c.baa.marshal(c.baa.Codec(ApplicationXML), v)
 	if err != nil {
 		c.Error(err)
 		return
@@ -510,49 +531,205 @@ func (c *Context) XML(code int, v interface{}) {
 	c.Resp.Write(re)
 }
 
+// Stream sends a chunked response with status code, step is called repeatedly
+// to write the next chunk until it returns false, every chunk is flushed.
+// It returns the context error when the client is gone, or the first write error.
+func (c *Context) Stream(code int, contentType string, step func(w io.Writer) bool) error {
+	c.Resp.Header().Set("Content-Type", contentType)
+	c.Resp.Header().Del("Content-Length")
+	c.Resp.WriteHeader(code)
+	w := &streamWriter{w: c.Resp}
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		default:
+		}
+		keepOpen := step(w)
+		if w.err != nil {
+			return w.err
+		}
+		c.Resp.Flush()
+		if !keepOpen {
+			return nil
+		}
+	}
+}
+
+// JSONLines sends values received from ch as newline delimited JSON with status code,
+// it returns when ch is closed, the client is gone, or a value cannot be encoded.
+func (c *Context) JSONLines(code int, ch <-chan interface{}) error {
+	codec := c.baa.Codec(ApplicationJSON)
+	c.Resp.Header().Set("Content-Type", ApplicationNDJSON)
+	c.Resp.Header().Del("Content-Length")
+	c.Resp.WriteHeader(code)
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			re, err := codec.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if _, err = c.Resp.Write(append(re, '\n')); err != nil {
+				return err
+			}
+			c.Resp.Flush()
+		}
+	}
+}
+
+// streamWriter keeps the first write error of a stream
+type streamWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.err = err
+	return n, err
+}
+
 // HTML write render data by html template engine use context.store
 // it is a alias of c.Render
 func (c *Context) HTML(code int, tpl string) {
 	c.Render(code, tpl)
 }
 
-// Render write render data by html template engine use context.store
+// Render write render data by html template engine use context.store,
+// the output is streamed to response if stripping blank lines is disabled.
+// Streamed output is buffered in debug mode, otherwise the first renderBufferSize bytes are buffered,
+// template errors before the output is sent are handled by the error handler.
 func (c *Context) Render(code int, tpl string) {
-	re, err := c.Fetch(tpl)
-	if err != nil {
-		c.Error(err)
+	if c.baa.stripBlankLines {
+		re, err := c.Fetch(tpl)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
+		c.Resp.WriteHeader(code)
+		c.Resp.Write(re)
 		return
 	}
-	c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
-	c.Resp.WriteHeader(code)
-	c.Resp.Write(re)
+
+	w := &renderWriter{c: c, code: code}
+	if !c.baa.debug {
+		w.limit = renderBufferSize
+	}
+	if err := c.baa.Render().Render(w, tpl, c.Gets()); err != nil {
+		if !w.wrote {
+			c.Error(err)
+			return
+		}
+		// the response is partly sent, the error can only be logged
+		c.baa.Logger().Println(err)
+		return
+	}
+	w.Flush()
 }
 
-// Fetch render data by html template engine use context.store and returns data
+// Fetch render data by html template engine use context.store and returns data,
+// blank lines are removed unless it is disabled by Baa.SetStripBlankLines.
 func (c *Context) Fetch(tpl string) ([]byte, error) {
 	buf := new(bytes.Buffer)
+	if !c.baa.stripBlankLines {
+		if err := c.baa.Render().Render(buf, tpl, c.Gets()); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
 
-	if err := c.baa.Render().Render(buf, tpl, c.Gets()); err != nil {
+	// clear go template generated black lines
+	w := &blankLineWriter{w: buf}
+	if err := c.baa.Render().Render(w, tpl, c.Gets()); err != nil {
 		return nil, err
 	}
+	w.Flush()
+	return buf.Bytes(), nil
+}
 
-	// clear go template generated black lines
-	nbuf := new(bytes.Buffer)
-	r := bufio.NewReader(buf)
-	for {
-		line, _, err := r.ReadLine()
-		if err != nil {
+// renderBufferSize is the size of streamed render output buffered before it is sent
+const renderBufferSize = 4096
+
+// renderWriter buffers render output, the response header is sent with the first output
+type renderWriter struct {
+	c     *Context
+	code  int
+	buf   bytes.Buffer
+	limit int // output is sent when buf reaches limit, 0 means buffer all
+	wrote bool
+}
+
+func (w *renderWriter) Write(p []byte) (int, error) {
+	if w.wrote {
+		return w.c.Resp.Write(p)
+	}
+	w.buf.Write(p)
+	if w.limit > 0 && w.buf.Len() >= w.limit {
+		return len(p), w.Flush()
+	}
+	return len(p), nil
+}
+
+// Flush sends the header and buffered output
+func (w *renderWriter) Flush() error {
+	if !w.wrote {
+		w.wrote = true
+		w.c.Resp.Header().Set("Content-Type", TextHTMLCharsetUTF8)
+		w.c.Resp.WriteHeader(w.code)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.c.Resp.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// blankLineWriter writes lines which are not blank, every line ends with \n
+type blankLineWriter struct {
+	w    io.Writer
+	line []byte
+}
+
+func (w *blankLineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
 			break
 		}
-		clearLine := strings.TrimSpace(string(line))
-		if len(clearLine) == 0 {
-			continue
+		w.line = append(w.line, p[:i]...)
+		p = p[i+1:]
+		if err := w.Flush(); err != nil {
+			return 0, err
 		}
-		nbuf.Write(line)
-		nbuf.WriteRune('\n')
 	}
+	return n, nil
+}
 
-	return nbuf.Bytes(), nil
+// Flush writes the buffered line if it is not blank
+func (w *blankLineWriter) Flush() error {
+	line := bytes.TrimSuffix(w.line, []byte{'\r'})
+	w.line = w.line[:0]
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+	if _, err := w.w.Write(line); err != nil {
+		return err
+	}
+	_, err := w.w.Write([]byte{'\n'})
+	return err
 }
 
 // Redirect redirects the request using http.Redirect with status code.
@@ -564,6 +741,104 @@ func (c *Context) Redirect(code int, url string) error {
 	return nil
 }
 
+// File sends the file at path, Range and If-Modified-Since requests are supported.
+// It invokes the NotFound handler when the file not exists or is a directory.
+func (c *Context) File(path string) {
+	c.sendFile(path, "", "")
+}
+
+// Attachment sends the file at path as a download named name,
+// the base name of path is used when name is empty.
+func (c *Context) Attachment(path, name string) {
+	c.sendFile(path, "attachment", name)
+}
+
+// Inline sends the file at path to display in browser with file name name,
+// the base name of path is used when name is empty.
+func (c *Context) Inline(path, name string) {
+	c.sendFile(path, "inline", name)
+}
+
+// sendFile sends the file at path, Content-Disposition of typ is set after the file is opened
+func (c *Context) sendFile(path, typ, name string) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.NotFound()
+			return
+		}
+		c.Error(err)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if fi.IsDir() {
+		c.NotFound()
+		return
+	}
+	if typ != "" {
+		c.contentDisposition(typ, path, name)
+	}
+	c.ServeContent(fi.Name(), fi.ModTime(), f)
+}
+
+// ServeContent sends content with name and modtime, it is a wrapper of http.ServeContent,
+// the Content-Type is detected by extension of name when it is not set.
+func (c *Context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.Resp, c.Req, name, modtime, content)
+}
+
+// contentDisposition sets Content-Disposition header by RFC 6266
+func (c *Context) contentDisposition(typ, path, name string) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	c.Resp.Header().Set("Content-Disposition", contentDisposition(typ, name))
+}
+
+// contentDisposition returns the Content-Disposition value of file name,
+// non ASCII names have an ASCII fallback and an UTF-8 encoded filename* parameter.
+func contentDisposition(typ, name string) string {
+	var fallback, encoded strings.Builder
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch >= 0x80 || ch < 0x20 || ch == 0x7f:
+			ascii = false
+			if ch < 0x80 || ch >= 0xc0 {
+				fallback.WriteByte('_')
+			}
+		case ch == '"' || ch == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteByte(ch)
+		default:
+			fallback.WriteByte(ch)
+		}
+		if isAttrChar(ch) {
+			encoded.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", ch)
+		}
+	}
+	if ascii {
+		return typ + `; filename="` + fallback.String() + `"`
+	}
+	return typ + `; filename="` + fallback.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isAttrChar reports whether ch is an attr-char of RFC 5987
+func isAttrChar(ch byte) bool {
+	if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", ch) >= 0
+}
+
 // RemoteAddr returns more real IP address.
 func (c *Context) RemoteAddr() string {
 	var addr string
@@ -661,15 +936,37 @@ func (c *Context) ParseForm(maxSize int64) error {
 	if c.Req.Form != nil {
 		return nil
 	}
+	c.rewindBody()
 	contentType := c.Req.Header.Get("Content-Type")
 	if (c.Req.Method == "POST" || c.Req.Method == "PUT") &&
 		len(contentType) > 0 && strings.Contains(contentType, MultipartForm) {
 		if maxSize == 0 {
-			maxSize = defaultMaxMemory
+			maxSize = c.baa.maxMemory
 		}
-		return c.Req.ParseMultipartForm(maxSize)
+		return c.checkBodyErr(c.Req.ParseMultipartForm(maxSize))
+	}
+	return c.checkBodyErr(c.Req.ParseForm())
+}
+
+// checkBodyErr returns ErrBodyTooLarge for err when the body limit is exceeded
+func (c *Context) checkBodyErr(err error) error {
+	if err != nil && c.bodyTooLarge() {
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+// onRelease registers fn called when the request is served, before the context is reused
+func (c *Context) onRelease(fn func()) {
+	c.releases = append(c.releases, fn)
+}
+
+// release calls funcs registered by onRelease
+func (c *Context) release() {
+	for _, fn := range c.releases {
+		fn()
 	}
-	return c.Req.ParseForm()
+	c.releases = c.releases[:0]
 }
 
 // Next execute next handler
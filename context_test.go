@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -604,6 +605,164 @@ func TestContextUnits(t *testing.T) {
 	})
 }
 
+func TestContextStream(t *testing.T) {
+	Convey("stream chunks", t, func() {
+		b := New()
+		var size int64
+		b.Get("/stream", func(c *Context) {
+			i := 0
+			err := c.Stream(200, TextPlainCharsetUTF8, func(w io.Writer) bool {
+				i++
+				fmt.Fprintf(w, "chunk%d\n", i)
+				return i < 3
+			})
+			So(err, ShouldBeNil)
+			size = c.Resp.Size()
+		})
+		w := serveRequest(b, "GET", "/stream")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Flushed, ShouldBeTrue)
+		So(w.Body.String(), ShouldEqual, "chunk1\nchunk2\nchunk3\n")
+		So(size, ShouldEqual, 21)
+	})
+
+	Convey("stream json lines", t, func() {
+		b := New()
+		b.Get("/lines", func(c *Context) {
+			ch := make(chan interface{})
+			go func() {
+				for i := 1; i <= 2; i++ {
+					ch <- map[string]int{"id": i}
+				}
+				close(ch)
+			}()
+			So(c.JSONLines(200, ch), ShouldBeNil)
+		})
+		w := serveRequest(b, "GET", "/lines")
+		So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationNDJSON)
+		So(w.Body.String(), ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+	})
+
+	Convey("stop stream when client is gone", t, func() {
+		b := New()
+		ctx, cancel := context.WithCancel(context.Background())
+		b.Get("/stream", func(c *Context) {
+			err := c.Stream(200, TextPlain, func(w io.Writer) bool {
+				w.Write([]byte("x"))
+				cancel()
+				return true
+			})
+			So(err, ShouldEqual, context.Canceled)
+			So(c.JSONLines(200, make(chan interface{})), ShouldEqual, context.Canceled)
+		})
+		req, _ := http.NewRequest("GET", "/stream", nil)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req.WithContext(ctx))
+		So(w.Body.String(), ShouldEqual, "x")
+	})
+}
+
+func TestContextServeFile(t *testing.T) {
+	Convey("serve file", t, func() {
+		b := New()
+		b.Get("/file", func(c *Context) {
+			c.File("_fixture/index1.html")
+		})
+		b.Get("/dir", func(c *Context) {
+			c.File("_fixture")
+		})
+		b.Get("/missing", func(c *Context) {
+			c.File("_fixture/missing.txt")
+		})
+		b.Get("/download", func(c *Context) {
+			c.Attachment("_fixture/img/baa.jpg", "")
+		})
+		b.Get("/download2", func(c *Context) {
+			c.Attachment("_fixture/img/baa.jpg", "报告 \"2024\".jpg")
+		})
+		b.Get("/inline", func(c *Context) {
+			c.Inline("_fixture/img/baa.jpg", "photo.jpg")
+		})
+		b.Get("/download-missing", func(c *Context) {
+			c.Attachment("_fixture/missing.jpg", "")
+		})
+		b.Get("/content", func(c *Context) {
+			c.ServeContent("data.txt", time.Time{}, strings.NewReader("0123456789"))
+		})
+
+		w := serveRequest(b, "GET", "/file")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Type"), ShouldStartWith, TextHTML)
+		So(w.Header().Get("Last-Modified"), ShouldNotBeEmpty)
+		So(serveRequest(b, "GET", "/dir").Code, ShouldEqual, http.StatusNotFound)
+		So(serveRequest(b, "GET", "/missing").Code, ShouldEqual, http.StatusNotFound)
+
+		w = serveRequest(b, "GET", "/download")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Disposition"), ShouldEqual, `attachment; filename="baa.jpg"`)
+		w = serveRequest(b, "GET", "/download2")
+		So(w.Header().Get("Content-Disposition"), ShouldEqual,
+			`attachment; filename="__ \"2024\".jpg"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%20%222024%22.jpg`)
+		w = serveRequest(b, "GET", "/inline")
+		So(w.Header().Get("Content-Disposition"), ShouldEqual, `inline; filename="photo.jpg"`)
+		w = serveRequest(b, "GET", "/download-missing")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Header().Get("Content-Disposition"), ShouldBeEmpty)
+
+		w = serveRequest(b, "GET", "/content", "Range", "bytes=2-4")
+		So(w.Code, ShouldEqual, http.StatusPartialContent)
+		So(w.Body.String(), ShouldEqual, "234")
+		So(w.Header().Get("Content-Type"), ShouldStartWith, TextPlain)
+
+		w = serveRequest(b, "GET", "/file", "If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		So(w.Code, ShouldEqual, http.StatusNotModified)
+	})
+}
+
+func TestContextCacheBody(t *testing.T) {
+	Convey("read cached body many times", t, func() {
+		b := New()
+		b.Post("/json", BodyCache(1024), func(c *Context) {
+			s, _ := c.Body().String()
+			So(s, ShouldEqual, `{"name":"baa"}`)
+			var v map[string]string
+			So(c.QueryJSON(&v), ShouldBeNil)
+			So(v["name"], ShouldEqual, "baa")
+			s, _ = c.Body().String()
+			So(s, ShouldEqual, `{"name":"baa"}`)
+		})
+		b.Post("/form", BodyCache(1024), func(c *Context) {
+			s, _ := c.Body().String()
+			So(s, ShouldEqual, "name=baa")
+			So(c.Posts()["name"], ShouldEqual, "baa")
+			s, _ = c.Body().String()
+			So(s, ShouldEqual, "name=baa")
+			c.String(200, "ok")
+		})
+		b.Post("/small", BodyCache(4), func(c *Context) {
+			c.String(200, "ok")
+		})
+
+		req, _ := http.NewRequest("POST", "/json", strings.NewReader(`{"name":"baa"}`))
+		req.Header.Set("Content-Type", ApplicationJSON)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		req, _ = http.NewRequest("POST", "/form", strings.NewReader("name=baa"))
+		req.Header.Set("Content-Type", ApplicationForm)
+		w = httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "ok")
+
+		req, _ = http.NewRequest("POST", "/small", strings.NewReader("12345"))
+		req.ContentLength = -1
+		w = httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+}
+
 func TestContextContext(t *testing.T) {
 	Convey("context cancel", t, func() {
 		c.Req, _ = http.NewRequest("GET", "/context", nil)
@@ -0,0 +1,96 @@
+package baa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error with HTTP status code, it is understood by the default error handler.
+// Message and Details are sent to client, Internal is only shown in debug mode.
+type HTTPError struct {
+	Code     int         `json:"code"`
+	Message  string      `json:"message"`
+	Internal error       `json:"-"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// NewHTTPError create a HTTPError, message defaults to the status text of code
+func NewHTTPError(code int, message ...string) *HTTPError {
+	e := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+// Error returns the error message
+func (e *HTTPError) Error() string {
+	if e.Internal == nil {
+		return fmt.Sprintf("code=%d, message=%s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("code=%d, message=%s, internal=%v", e.Code, e.Message, e.Internal)
+}
+
+// Unwrap returns the internal error
+func (e *HTTPError) Unwrap() error {
+	return e.Internal
+}
+
+// SetInternal sets the internal error, returns the error itself
+func (e *HTTPError) SetInternal(err error) *HTTPError {
+	e.Internal = err
+	return e
+}
+
+// SetDetails sets the details sent to client, returns the error itself
+func (e *HTTPError) SetDetails(v interface{}) *HTTPError {
+	e.Details = v
+	return e
+}
+
+// toHTTPError returns the HTTPError in err chain,
+// BindErrors is converted to a bad request error with field errors as details,
+// ValidationErrors is converted to an unprocessable entity error with field errors as details,
+// ErrUnsupportedMediaType and ErrBodyTooLarge are converted to errors of their status code,
+// other errors are converted to an internal server error.
+func toHTTPError(err error) *HTTPError {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return NewHTTPError(http.StatusUnsupportedMediaType).SetInternal(err)
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge).SetInternal(err)
+	}
+	var be BindErrors
+	if errors.As(err, &be) {
+		return NewHTTPError(http.StatusBadRequest).SetInternal(err).SetDetails(be)
+	}
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		return NewHTTPError(http.StatusUnprocessableEntity).SetInternal(err).SetDetails(ve)
+	}
+	return NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+}
+
+// PanicError is the error of panic recovered in handlers
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns the panic value and stack
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
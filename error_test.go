@@ -0,0 +1,72 @@
+package baa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHTTPError1(t *testing.T) {
+	Convey("http error", t, func() {
+		e := NewHTTPError(http.StatusNotFound)
+		So(e.Message, ShouldEqual, "Not Found")
+		So(e.Error(), ShouldEqual, "code=404, message=Not Found")
+		e = NewHTTPError(http.StatusConflict, "user exists").SetInternal(errors.New("duplicate key"))
+		So(e.Error(), ShouldEqual, "code=409, message=user exists, internal=duplicate key")
+		So(errors.Unwrap(e).Error(), ShouldEqual, "duplicate key")
+		So(toHTTPError(errors.New("BOMB")).Code, ShouldEqual, http.StatusInternalServerError)
+
+		he := toHTTPError(fmt.Errorf("read body: %w", ErrBodyTooLarge))
+		So(he.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		he.SetDetails("changed")
+		So(toHTTPError(ErrBodyTooLarge).Details, ShouldBeNil)
+		So(toHTTPError(ErrUnsupportedMediaType).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+	})
+}
+
+func TestHTTPError2(t *testing.T) {
+	Convey("default error handler understand http error", t, func() {
+		b2 := New()
+		b2.Get("/conflict", func(c *Context) {
+			c.Error(NewHTTPError(http.StatusConflict, "user exists").
+				SetInternal(errors.New("duplicate key")).
+				SetDetails(map[string]string{"field": "name"}))
+		})
+		b2.Get("/bomb", func(c *Context) {
+			c.Error(errors.New("BOMB"))
+		})
+		Convey("plain text", func() {
+			w := serveRequest(b2, "GET", "/conflict", "Accept", "text/html")
+			So(w.Code, ShouldEqual, http.StatusConflict)
+			So(w.Body.String(), ShouldEqual, "user exists: duplicate key\n")
+
+			b2.SetDebug(false)
+			w = serveRequest(b2, "GET", "/conflict", "Accept", "text/plain")
+			So(w.Body.String(), ShouldEqual, "user exists\n")
+			w = serveRequest(b2, "GET", "/bomb", "Accept", "")
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldEqual, "Internal Server Error\n")
+		})
+
+		Convey("json", func() {
+			w := serveRequest(b2, "GET", "/conflict", "Accept", "application/json, text/plain, */*")
+			So(w.Code, ShouldEqual, http.StatusConflict)
+			So(w.Header().Get("Content-Type"), ShouldEqual, ApplicationJSONCharsetUTF8)
+			var body map[string]interface{}
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body["message"], ShouldEqual, "user exists")
+			So(body["internal"], ShouldEqual, "duplicate key")
+			So(body["details"], ShouldResemble, map[string]interface{}{"field": "name"})
+
+			b2.SetDebug(false)
+			w = serveRequest(b2, "GET", "/conflict", "Accept", "application/json")
+			body = nil
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body["internal"], ShouldBeNil)
+		})
+	})
+}
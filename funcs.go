@@ -0,0 +1,110 @@
+package baa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// CSRFKey is the key of CSRF token in template data, it is used by csrfField.
+const CSRFKey = "_csrf"
+
+// asset is a static file route, it is used to fingerprint asset paths
+type asset struct {
+	prefix string
+	fsys   fs.FS
+}
+
+// FuncMap returns the default template funcs, they are injected into the default Render
+// when it is registered, with lower precedence than its own funcs. Other renders are not changed,
+// they can add FuncMap to their funcs before their own ones:
+//
+//	{{ url "name" args... }}     URL of named route, see URLFor
+//	{{ static "/assets/app.js" }}  path of static file with version fingerprint
+//	{{ csrfField . }}            hidden input of CSRF token stored in data by CSRFKey
+//	{{ json .v }}                v encoded as JSON for script
+//	{{ safeHTML .s }}            s is not escaped
+func (b *Baa) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url":       b.URLFor,
+		"static":    b.assetPath,
+		"csrfField": csrfField,
+		"json":      jsonJS,
+		"safeHTML":  safeHTML,
+	}
+}
+
+// addAsset records static file route for fingerprinting
+func (b *Baa) addAsset(prefix string, fsys fs.FS) {
+	if len(prefix) > 1 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	b.assets = append(b.assets, &asset{prefix: prefix, fsys: fsys})
+}
+
+// assetPath returns path with ?v=fingerprint of the file content,
+// path is returned as it is if it is not a file of static routes.
+// Fingerprints are cached except in debug mode.
+func (b *Baa) assetPath(path string) string {
+	if !b.debug {
+		if v, ok := b.fingerprints.Load(path); ok {
+			return v.(string)
+		}
+	}
+	p := path
+	for _, a := range b.assets {
+		if !strings.HasPrefix(path, a.prefix+"/") {
+			continue
+		}
+		if hash := fingerprint(a.fsys, cleanFSPath(path[len(a.prefix):])); hash != "" {
+			p = path + "?v=" + hash
+			break
+		}
+	}
+	if !b.debug {
+		b.fingerprints.Store(path, p)
+	}
+	return p
+}
+
+// fingerprint returns short hash of file content, empty if the file cannot be read
+func fingerprint(fsys fs.FS, name string) string {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// csrfField returns hidden input of CSRF token in data
+func csrfField(data interface{}) template.HTML {
+	var token interface{}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		token = v[CSRFKey]
+	case map[string]string:
+		token = v[CSRFKey]
+	}
+	s, _ := token.(string)
+	return template.HTML(`<input type="hidden" name="` + CSRFKey + `" value="` + template.HTMLEscapeString(s) + `">`)
+}
+
+// jsonJS returns v encoded as JSON, it can be used in script
+func jsonJS(v interface{}) (template.JS, error) {
+	re, err := json.Marshal(v)
+	return template.JS(re), err
+}
+
+// safeHTML returns s as HTML which is not escaped
+func safeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
@@ -0,0 +1,85 @@
+package baa
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testFuncsRender struct {
+	funcs template.FuncMap
+}
+
+func (r *testFuncsRender) Render(w io.Writer, tpl string, data interface{}) error {
+	return nil
+}
+
+func (r *testFuncsRender) Funcs(funcs template.FuncMap) {
+	for k, v := range funcs {
+		r.funcs[k] = v
+	}
+}
+
+func TestFuncMap1(t *testing.T) {
+	Convey("default template funcs", t, func() {
+		assets := fstest.MapFS{
+			"app.js": {Data: []byte("console.log('baa')")},
+		}
+		views := fstest.MapFS{
+			"page.html": {Data: []byte(`{{ url "user" 7 }}|{{ static "/assets/app.js" }}|{{ static "/assets/none.js" }}|` +
+				`{{ csrfField . }}|<script>var v = {{ json .data }};</script>|{{ safeHTML "<b>x</b>" }}|{{ upper "x" }}`)},
+		}
+
+		b := New()
+		b.debug = false
+		b.StaticFS("/assets", assets, false, nil)
+		b.Get("/user/:id", func(c *Context) {}).Name("user")
+		r := NewRender(RenderOptions{FS: views})
+		r.Funcs(map[string]interface{}{"upper": func(s string) string { return "X" }})
+		b.SetDI("render", r)
+		b.Get("/", func(c *Context) {
+			c.Set(CSRFKey, `t"k`)
+			c.Set("data", map[string]int{"n": 1})
+			c.HTML(200, "page.html")
+		})
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, `/user/7|/assets/app.js?v=`+fingerprint(assets, "app.js")+`|/assets/none.js|`+
+			`<input type="hidden" name="_csrf" value="t&#34;k">|<script>var v = {"n":1};</script>|<b>x</b>|X`+"\n")
+		So(fingerprint(assets, "app.js"), ShouldHaveLength, 8)
+
+		_, ok := b.fingerprints.Load("/assets/app.js")
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("render funcs override default funcs", t, func() {
+		views := fstest.MapFS{
+			"page.html": {Data: []byte(`{{ url "home" }}|{{ safeHTML "x" }}`)},
+		}
+		b := New()
+		r := NewRender(RenderOptions{FS: views, Funcs: template.FuncMap{"url": func(string) string { return "opt" }}})
+		r.Funcs(template.FuncMap{"safeHTML": func(string) string { return "funcs" }})
+		b.SetDI("render", r)
+		b.Get("/", func(c *Context) {
+			c.HTML(200, "page.html")
+		})
+		So(serveRequest(b, "GET", "/").Body.String(), ShouldEqual, "opt|funcs\n")
+	})
+
+	Convey("other renders keep their funcs", t, func() {
+		b := New()
+		url := func() string { return "own" }
+		r := &testFuncsRender{funcs: template.FuncMap{"url": url}}
+		b.SetDI("render", r)
+		So(r.funcs, ShouldHaveLength, 1)
+		So(r.funcs["url"].(func() string)(), ShouldEqual, "own")
+	})
+}
@@ -0,0 +1,158 @@
+package baa
+
+import "io/fs"
+
+// Group is a list of routes has same prefix, handle chain and name prefix.
+// It is safe to use outside a callback and can be nested,
+// a nested group inherits handlers and name prefix of its parent.
+type Group struct {
+	baa        *Baa
+	pattern    string
+	namePrefix string
+	handlers   []HandlerFunc
+}
+
+// groupRoute is a route node registered by group
+type groupRoute struct {
+	RouteNode
+	namePrefix string
+}
+
+// newRouteGroup create a route group
+func newRouteGroup(b *Baa, pattern, namePrefix string, handlers []HandlerFunc) *Group {
+	g := new(Group)
+	g.baa = b
+	g.pattern = pattern
+	g.namePrefix = namePrefix
+	g.handlers = make([]HandlerFunc, len(handlers))
+	copy(g.handlers, handlers)
+	return g
+}
+
+// Name set name of route with the group name prefix
+func (r *groupRoute) Name(name string) {
+	if name == "" {
+		return
+	}
+	r.RouteNode.Name(r.namePrefix + name)
+}
+
+// SetNamePrefix sets the prefix added to the name of routes in group
+func (g *Group) SetNamePrefix(prefix string) *Group {
+	g.namePrefix = prefix
+	return g
+}
+
+// Use appends middlewares to the group handle chain,
+// only affects routes registered after it.
+func (g *Group) Use(m ...Middleware) *Group {
+	g.handlers = append(g.handlers, wrapMiddlewares(m)...)
+	return g
+}
+
+// Group create a nested group, it inherits handlers and name prefix of g
+func (g *Group) Group(pattern string, m ...Middleware) *Group {
+	h := wrapMiddlewares(m)
+	handlers := make([]HandlerFunc, 0, len(g.handlers)+len(h))
+	handlers = append(handlers, g.handlers...)
+	handlers = append(handlers, h...)
+	return newRouteGroup(g.baa, g.pattern+pattern, g.namePrefix, handlers)
+}
+
+// Route is a shortcut for same handlers but different HTTP methods.
+func (g *Group) Route(pattern, methods string, h ...HandlerFunc) RouteNode {
+	var ru RouteNode
+	for _, m := range splitMethods(methods) {
+		ru = g.add(m, pattern, h)
+	}
+	return ru
+}
+
+// Any is a shortcut for g.Route(pattern, "*", handlers)
+func (g *Group) Any(pattern string, h ...HandlerFunc) RouteNode {
+	return g.Route(pattern, "*", h...)
+}
+
+// Delete is a shortcut for g.Route(pattern, "DELETE", handlers)
+func (g *Group) Delete(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("DELETE", pattern, h)
+}
+
+// Get is a shortcut for g.Route(pattern, "GET", handlers)
+func (g *Group) Get(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("GET", pattern, h)
+}
+
+// Head is a shortcut for g.Route(pattern, "HEAD", handlers)
+func (g *Group) Head(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("HEAD", pattern, h)
+}
+
+// Options is a shortcut for g.Route(pattern, "OPTIONS", handlers)
+func (g *Group) Options(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("OPTIONS", pattern, h)
+}
+
+// Patch is a shortcut for g.Route(pattern, "PATCH", handlers)
+func (g *Group) Patch(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("PATCH", pattern, h)
+}
+
+// Post is a shortcut for g.Route(pattern, "POST", handlers)
+func (g *Group) Post(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("POST", pattern, h)
+}
+
+// Put is a shortcut for g.Route(pattern, "PUT", handlers)
+func (g *Group) Put(pattern string, h ...HandlerFunc) RouteNode {
+	return g.add("PUT", pattern, h)
+}
+
+// Static set static file route in group
+// h used for set Expries ...
+func (g *Group) Static(prefix string, dir string, index bool, h HandlerFunc) {
+	if prefix == "" {
+		panic("baa.Group.Static prefix can not be empty")
+	}
+	if dir == "" {
+		panic("baa.Group.Static dir can not be empty")
+	}
+	g.StaticWithOptions(prefix, StaticOptions{Dir: dir, Index: index, Handler: h})
+}
+
+// StaticFS set static file route in group serves files from fsys, such as embed.FS or os.DirFS,
+// file paths are relative to the root of fsys. index lists files of directory,
+// h is called before serving, it can be used for set headers.
+func (g *Group) StaticFS(prefix string, fsys fs.FS, index bool, h HandlerFunc) {
+	if prefix == "" {
+		panic("baa.Group.StaticFS prefix can not be empty")
+	}
+	if fsys == nil {
+		panic("baa.Group.StaticFS fsys can not be nil")
+	}
+	g.StaticWithOptions(prefix, StaticOptions{FS: fsys, Index: index, Handler: h})
+}
+
+// StaticWithOptions set static file route in group with options
+func (g *Group) StaticWithOptions(prefix string, opt StaticOptions) {
+	if prefix == "" {
+		panic("baa.Group.StaticWithOptions prefix can not be empty")
+	}
+	if opt.Dir == "" && opt.FS == nil {
+		panic("baa.Group.StaticWithOptions Dir or FS must be set")
+	}
+	fsys := opt.fs()
+	g.baa.addAsset(g.pattern+prefix, fsys)
+	g.Get(prefix+"*", newStatic(g.pattern+prefix, fsys, opt))
+}
+
+// add registers a route with group prefix and handle chain
+func (g *Group) add(method, pattern string, h []HandlerFunc) RouteNode {
+	handlers := make([]HandlerFunc, 0, len(g.handlers)+len(h))
+	handlers = append(handlers, g.handlers...)
+	handlers = append(handlers, h...)
+	return &groupRoute{
+		RouteNode:  g.baa.Router().Add(method, g.pattern+pattern, handlers),
+		namePrefix: g.namePrefix,
+	}
+}
@@ -0,0 +1,77 @@
+package baa
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGroup1(t *testing.T) {
+	Convey("route group object", t, func() {
+		b2 := New()
+		mark := func(v string) HandlerFunc {
+			return func(c *Context) {
+				c.Resp.Header().Add("X-Mark", v)
+			}
+		}
+		api := b2.NewGroup("/api", mark("api")).SetNamePrefix("api.")
+		api.Get("/ping", func(c *Context) {
+			c.String(200, "pong")
+		}).Name("ping")
+		v1 := api.Group("/v1", mark("v1"), func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Mark", "v1 func")
+		})
+		api.Use(mark("late"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Mark", "http")
+		}))
+		api.Post("/late", func(c *Context) {
+			c.String(200, "late")
+		})
+		v1.Get("/user/:id", func(c *Context) {
+			c.String(200, c.Param("id"))
+		}).Name("user")
+		v1.Route("/items", "GET,POST", func(c *Context) {
+			c.String(200, c.Req.Method)
+		})
+		v1.Static("/assets", "./_fixture", false, nil)
+
+		w := serveRequest(b2, "GET", "/api/ping")
+		So(w.Body.String(), ShouldEqual, "pong")
+		So(w.Header()["X-Mark"], ShouldResemble, []string{"api"})
+
+		w = serveRequest(b2, "GET", "/api/v1/user/12")
+		So(w.Body.String(), ShouldEqual, "12")
+		So(w.Header()["X-Mark"], ShouldResemble, []string{"api", "v1", "v1 func"})
+
+		w = serveRequest(b2, "POST", "/api/late")
+		So(w.Header()["X-Mark"], ShouldResemble, []string{"api", "late", "http"})
+
+		w = serveRequest(b2, "POST", "/api/v1/items")
+		So(w.Body.String(), ShouldEqual, "POST")
+
+		w = serveRequest(b2, "GET", "/api/v1/assets/favicon.ico")
+		So(w.Code, ShouldEqual, http.StatusOK)
+
+		b2.NewGroup("/h", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Mark", "http")
+		})).Get("/x", func(c *Context) {
+			c.String(200, "x")
+		})
+		w = serveRequest(b2, "GET", "/h/x")
+		So(w.Body.String(), ShouldEqual, "x")
+		So(w.Header()["X-Mark"], ShouldResemble, []string{"http"})
+
+		So(b2.URLFor("api.ping"), ShouldEqual, "/api/ping")
+		So(b2.URLFor("api.user", 12), ShouldEqual, "/api/v1/user/12")
+
+		Convey("work with closure group", func() {
+			b2.Group("/admin", func() {
+				g := b2.NewGroup("/users")
+				g.Get("/list", f)
+			})
+			w := serveRequest(b2, "GET", "/admin/users/list")
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -0,0 +1,155 @@
+package baa
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Offer is a response format offered in content negotiation,
+// Handler is called with the status code when the media type is chosen.
+type Offer struct {
+	MediaType string
+	Handler   func(code int)
+}
+
+// acceptRange is a media range in Accept header
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// Negotiate chooses the best offer for the Accept header and calls its handler with code,
+// it sets Vary: Accept, and invokes the not acceptable handler when nothing matches.
+// Offers are in server preference order, the first one is chosen when Accept is empty.
+//
+// Example:
+//
+//	c.Negotiate(200,
+//		baa.Offer{baa.ApplicationJSON, func(code int) { c.JSON(code, v) }},
+//		baa.Offer{baa.ApplicationXML, func(code int) { c.XML(code, v) }},
+//	)
+func (c *Context) Negotiate(code int, offers ...Offer) {
+	c.Resp.Header().Add("Vary", "Accept")
+	types := make([]string, len(offers))
+	for i := range offers {
+		types[i] = offers[i].MediaType
+	}
+	if i := negotiate(c.Req.Header.Get("Accept"), types); i >= 0 {
+		offers[i].Handler(code)
+		return
+	}
+	c.baa.NotAcceptable(c)
+}
+
+// NegotiateFormat returns the best media type in offers for the Accept header,
+// returns empty string when nothing matches.
+func (c *Context) NegotiateFormat(offers ...string) string {
+	if i := negotiate(c.Req.Header.Get("Accept"), offers); i >= 0 {
+		return offers[i]
+	}
+	return ""
+}
+
+// negotiate returns index of the best offer for accept header, -1 if nothing matches.
+// The best offer has the highest quality, then the most specific range,
+// then the range comes first in accept, then the offer comes first.
+func negotiate(accept string, offers []string) int {
+	if len(offers) == 0 {
+		return -1
+	}
+	if strings.TrimSpace(accept) == "" {
+		return 0
+	}
+	ranges := parseAccept(accept)
+
+	best := -1
+	var bestQ float64
+	var bestSpec, bestPos int
+	for i, offer := range offers {
+		typ, subtype := splitMediaType(offer)
+		q, spec, pos := -1.0, -1, 0
+		for j, r := range ranges {
+			s := -1
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			}
+			if s > spec {
+				q, spec, pos = r.q, s, j
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if best < 0 || q > bestQ || (q == bestQ && (spec > bestSpec || (spec == bestSpec && pos < bestPos))) {
+			best, bestQ, bestSpec, bestPos = i, q, spec, pos
+		}
+	}
+	return best
+}
+
+// parseAccept parses media ranges of Accept header
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype := splitMediaType(params[0])
+		if typ == "" {
+			continue
+		}
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if len(p) > 2 && (p[0] == 'q' || p[0] == 'Q') && p[1] == '=' {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// splitMediaType returns lower type and subtype of media type, params are ignored
+func splitMediaType(mediaType string) (string, string) {
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	i := strings.IndexByte(mediaType, '/')
+	if i < 0 {
+		if mediaType == "*" {
+			return "*", "*"
+		}
+		return "", ""
+	}
+	return mediaType[:i], mediaType[i+1:]
+}
+
+// SetNotAcceptable set not acceptable handler used by Context.Negotiate
+func (b *Baa) SetNotAcceptable(h HandlerFunc) {
+	b.notAcceptHandler = h
+}
+
+// NotAcceptable execute not acceptable handler
+func (b *Baa) NotAcceptable(c *Context) {
+	if b.notAcceptHandler != nil {
+		b.notAcceptHandler(c)
+		return
+	}
+	b.DefaultNotAcceptableHandler(c)
+}
+
+// DefaultNotAcceptableHandler invokes the default HTTP not acceptable handler.
+func (b *Baa) DefaultNotAcceptableHandler(c *Context) {
+	code := http.StatusNotAcceptable
+	msg := http.StatusText(code)
+	http.Error(c.Resp, msg, code)
+}
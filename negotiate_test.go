@@ -0,0 +1,62 @@
+package baa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegotiate1(t *testing.T) {
+	Convey("negotiate media type", t, func() {
+		offers := []string{ApplicationJSON, ApplicationXML, TextHTML}
+		So(negotiate("", offers), ShouldEqual, 0)
+		So(negotiate("text/html", offers), ShouldEqual, 2)
+		So(negotiate("application/xml;q=0.9, application/json;q=0.5", offers), ShouldEqual, 1)
+		So(negotiate("text/*, application/json;q=0.8", offers), ShouldEqual, 2)
+		So(negotiate("*/*;q=0.1, application/xml", offers), ShouldEqual, 1)
+		So(negotiate("*/*", offers), ShouldEqual, 0)
+		So(negotiate("application/json;q=0, */*", offers), ShouldEqual, 1)
+		So(negotiate("image/png", offers), ShouldEqual, -1)
+		So(negotiate("text/html", nil), ShouldEqual, -1)
+	})
+}
+
+func TestNegotiate2(t *testing.T) {
+	Convey("context negotiate", t, func() {
+		b := New()
+		b.Get("/", func(c *Context) {
+			c.Negotiate(http.StatusOK,
+				Offer{ApplicationJSON, func(code int) { c.JSON(code, map[string]string{"name": "baa"}) }},
+				Offer{TextPlain, func(code int) { c.String(code, "baa") }},
+			)
+		})
+		w := serveRequest(b, "GET", "/", "Accept", "text/plain, application/json;q=0.5")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "baa")
+		So(w.Header().Get("Vary"), ShouldEqual, "Accept")
+
+		w = serveRequest(b, "GET", "/", "Accept", "")
+		So(w.Body.String(), ShouldContainSubstring, `"name"`)
+
+		w = serveRequest(b, "GET", "/", "Accept", "image/png")
+		So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+
+		b.SetNotAcceptable(func(c *Context) {
+			c.String(http.StatusNotAcceptable, "no way")
+		})
+		w = serveRequest(b, "GET", "/", "Accept", "image/png")
+		So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+		So(w.Body.String(), ShouldEqual, "no way")
+	})
+
+	Convey("context negotiate format", t, func() {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", "application/xml, */*;q=0.1")
+		c := NewContext(httptest.NewRecorder(), req, New())
+		So(c.NegotiateFormat(ApplicationJSON, ApplicationXML), ShouldEqual, ApplicationXML)
+		req.Header.Set("Accept", "image/*")
+		So(c.NegotiateFormat(ApplicationJSON, ApplicationXML), ShouldEqual, "")
+	})
+}
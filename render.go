@@ -1,10 +1,17 @@
 package baa
 
 import (
+	"bytes"
+	"errors"
 	"html/template"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 // Renderer is the interface that wraps the Render method.
@@ -12,38 +19,271 @@ type Renderer interface {
 	Render(w io.Writer, tpl string, data interface{}) error
 }
 
-// Render default baa template engine
+// RenderOptions is the options of default template engine
+type RenderOptions struct {
+	// FS is the file system of templates, such as embed.FS, default is the OS file system.
+	FS fs.FS
+	// Dir is the root directory of templates in FS, template names are relative to it.
+	Dir string
+	// Extensions are the extensions of template files, default is [".html"].
+	// The extension can be omitted in template name.
+	Extensions []string
+	// Layout is the name of layout template, pages are rendered inside the layout.
+	// The layout shows the page by {{ yield . }}, or by blocks the page redefines.
+	Layout string
+	// Funcs is the FuncMap can be used in templates, funcs added by Render.Funcs override it.
+	Funcs template.FuncMap
+	// Reload parses templates on every render, otherwise parsed templates are cached.
+	// It is usually enabled in DEV Env.
+	Reload bool
+}
+
+// Render default baa template engine,
+// files start with "_" in Dir are partials, they can be included in every template by
+// {{ template "_name.html" . }}, name is the slash separated path relative to Dir.
 type Render struct {
+	opt      RenderOptions
+	fsys     fs.FS
+	funcs    template.FuncMap
+	defaults template.FuncMap // default funcs of Baa, they have the lowest precedence
+	cache    map[string]*template.Template
+	mu       sync.RWMutex
 }
 
-// Render ...
+// NewRender create a default template engine with options
+func NewRender(opt RenderOptions) *Render {
+	if len(opt.Extensions) == 0 {
+		opt.Extensions = []string{".html"}
+	}
+	r := &Render{
+		opt:   opt,
+		fsys:  opt.FS,
+		funcs: make(template.FuncMap),
+		cache: make(map[string]*template.Template),
+	}
+	if r.fsys != nil && opt.Dir != "" && opt.Dir != "." {
+		sub, err := fs.Sub(r.fsys, opt.Dir)
+		if err != nil {
+			panic("baa.NewRender invalid Dir: " + err.Error())
+		}
+		r.fsys = sub
+	}
+	r.funcs["yield"] = func(interface{}) (template.HTML, error) {
+		return "", errors.New("baa.Render yield called without layout")
+	}
+	for k, v := range opt.Funcs {
+		r.funcs[k] = v
+	}
+	return r
+}
+
+// Render renders template tpl with data to w
 func (r *Render) Render(w io.Writer, tpl string, data interface{}) error {
-	t, err := parseFile(tpl)
+	t, err := r.template(tpl)
 	if err != nil {
 		return err
 	}
+	if r.opt.Layout != "" && tpl != r.opt.Layout {
+		return t.ExecuteTemplate(w, r.opt.Layout, data)
+	}
 	return t.Execute(w, data)
 }
 
-// parseFile ...
-func parseFile(filename string) (*template.Template, error) {
-	var t *template.Template
-	b, err := ioutil.ReadFile(filename)
+// Funcs adds funcs to the FuncMap of templates, cached templates are dropped.
+func (r *Render) Funcs(funcs template.FuncMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, v := range funcs {
+		r.funcs[k] = v
+	}
+	r.cache = make(map[string]*template.Template)
+}
+
+// setDefaults sets the default funcs, they are overridden by funcs of options and Render.Funcs.
+func (r *Render) setDefaults(funcs template.FuncMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults = funcs
+	r.cache = make(map[string]*template.Template)
+}
+
+// Load parses all templates in Dir into cache, it reports template errors before serving.
+func (r *Render) Load() error {
+	return r.walk(func(name string) error {
+		if isPartial(name) || name == r.opt.Layout {
+			return nil
+		}
+		t, err := r.parse(name)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.cache[name] = t
+		r.mu.Unlock()
+		return nil
+	})
+}
+
+// template returns the parsed template of name from cache, or parses it
+func (r *Render) template(name string) (*template.Template, error) {
+	if !r.opt.Reload {
+		r.mu.RLock()
+		t := r.cache[name]
+		r.mu.RUnlock()
+		if t != nil {
+			return t, nil
+		}
+	}
+	t, err := r.parse(name)
 	if err != nil {
 		return nil, err
 	}
-	s := string(b)
-	name := filepath.Base(filename)
-	t = template.New(name)
-	_, err = t.Parse(s)
+	if !r.opt.Reload {
+		r.mu.Lock()
+		r.cache[name] = t
+		r.mu.Unlock()
+	}
+	return t, nil
+}
+
+// parse parses template name with partials and layout
+func (r *Render) parse(name string) (*template.Template, error) {
+	content, err := r.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	funcs := make(template.FuncMap, len(r.defaults)+len(r.funcs)+1)
+	for k, v := range r.defaults {
+		funcs[k] = v
+	}
+	for k, v := range r.funcs {
+		funcs[k] = v
+	}
+	r.mu.RUnlock()
+
+	var t *template.Template
+	if r.opt.Layout != "" && name != r.opt.Layout {
+		funcs["yield"] = func(data interface{}) (template.HTML, error) {
+			buf := new(bytes.Buffer)
+			err := t.ExecuteTemplate(buf, name, data)
+			return template.HTML(buf.String()), err
+		}
+	}
+	t = template.New(name).Funcs(funcs)
+
+	partials, err := r.partials()
 	if err != nil {
 		return nil, err
 	}
+	for _, p := range partials {
+		if p == name {
+			continue
+		}
+		if err = r.parseInto(t.New(p), p); err != nil {
+			return nil, err
+		}
+	}
+	if r.opt.Layout != "" && name != r.opt.Layout {
+		if err = r.parseInto(t.New(r.opt.Layout), r.opt.Layout); err != nil {
+			return nil, err
+		}
+	}
+	// page is parsed last, then it can redefine blocks of layout
+	if _, err = t.Parse(string(content)); err != nil {
+		return nil, err
+	}
 	return t, nil
 }
 
+// parseInto parses file of name into t
+func (r *Render) parseInto(t *template.Template, name string) error {
+	content, err := r.readFile(name)
+	if err != nil {
+		return err
+	}
+	_, err = t.Parse(string(content))
+	return err
+}
+
+// partials returns names of partial templates in Dir
+func (r *Render) partials() ([]string, error) {
+	if r.fsys == nil && r.opt.Dir == "" {
+		return nil, nil
+	}
+	var names []string
+	err := r.walk(func(name string) error {
+		if isPartial(name) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// walk calls fn with the slash separated name of every template file in Dir
+func (r *Render) walk(fn func(name string) error) error {
+	if r.fsys != nil {
+		return fs.WalkDir(r.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !r.isTemplate(name) {
+				return err
+			}
+			return fn(name)
+		})
+	}
+	dir := r.opt.Dir
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !r.isTemplate(file) {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(name))
+	})
+}
+
+// readFile reads template file of name, extensions are tried if name has no extension
+func (r *Render) readFile(name string) ([]byte, error) {
+	read := func(name string) ([]byte, error) {
+		if r.fsys != nil {
+			return fs.ReadFile(r.fsys, name)
+		}
+		return ioutil.ReadFile(filepath.Join(r.opt.Dir, filepath.FromSlash(name)))
+	}
+	content, err := read(name)
+	if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+		for _, ext := range r.opt.Extensions {
+			if content, err = read(name + ext); !errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+		}
+	}
+	return content, err
+}
+
+// isPartial reports whether template name is a partial
+func isPartial(name string) bool {
+	return strings.HasPrefix(path.Base(name), "_")
+}
+
+// isTemplate reports whether file has a template extension
+func (r *Render) isTemplate(file string) bool {
+	ext := filepath.Ext(file)
+	for _, v := range r.opt.Extensions {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
 // newRender create a render instance
 func newRender() *Render {
-	r := new(Render)
-	return r
+	return NewRender(RenderOptions{Reload: Env != PROD})
 }
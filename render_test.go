@@ -1,9 +1,16 @@
 package baa
 
 import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -41,3 +48,125 @@ func TestRender1(t *testing.T) {
 		So(w.Code, ShouldEqual, http.StatusInternalServerError)
 	})
 }
+
+func TestRender2(t *testing.T) {
+	Convey("render with layout, partials and funcs", t, func() {
+		r := NewRender(RenderOptions{
+			Dir:    "_fixture/views",
+			Layout: "layout.html",
+			Funcs: template.FuncMap{
+				"upper": strings.ToUpper,
+			},
+		})
+		So(r.Load(), ShouldBeNil)
+
+		buf := new(bytes.Buffer)
+		err := r.Render(buf, "index", map[string]interface{}{"name": "baa", "title": "Home"})
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "<html><head><title>Baa</title></head>\n<body><h1>Home</h1>Hello, BAA</body></html>\n")
+
+		buf.Reset()
+		err = r.Render(buf, "blocks.html", nil)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "<html><head><title>Blocks</title></head>\n<body>Page</body></html>\n")
+
+		So(r.Render(buf, "missing.html", nil), ShouldNotBeNil)
+
+		r.Funcs(template.FuncMap{"upper": strings.ToLower})
+		buf.Reset()
+		r.Render(buf, "index.html", map[string]interface{}{"name": "BAA"})
+		So(buf.String(), ShouldContainSubstring, "Hello, baa")
+	})
+
+	Convey("cache and reload templates", t, func() {
+		dir, err := ioutil.TempDir("", "baa")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "page.html")
+		ioutil.WriteFile(file, []byte("v1"), 0644)
+
+		cached := NewRender(RenderOptions{Dir: dir})
+		reload := NewRender(RenderOptions{Dir: dir, Reload: true})
+		render := func(r *Render) string {
+			buf := new(bytes.Buffer)
+			So(r.Render(buf, "page", nil), ShouldBeNil)
+			return buf.String()
+		}
+		So(render(cached), ShouldEqual, "v1")
+		So(render(reload), ShouldEqual, "v1")
+
+		ioutil.WriteFile(file, []byte("v2"), 0644)
+		So(render(cached), ShouldEqual, "v1")
+		So(render(reload), ShouldEqual, "v2")
+	})
+
+	Convey("yield without layout", t, func() {
+		dir, _ := ioutil.TempDir("", "baa")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("{{ yield . }}"), 0644)
+		r := NewRender(RenderOptions{Dir: dir})
+		So(r.Render(new(bytes.Buffer), "page.html", nil), ShouldNotBeNil)
+	})
+}
+
+func TestRender3(t *testing.T) {
+	Convey("render from fs.FS", t, func() {
+		fsys := fstest.MapFS{
+			"views/layout.html":   {Data: []byte("[{{ yield . }}]")},
+			"views/page.html":     {Data: []byte(`{{ template "_name.html" . }}!`)},
+			"views/_name.html":    {Data: []byte("{{ .name }}")},
+			"views/sub/page.html": {Data: []byte("sub")},
+		}
+		r := NewRender(RenderOptions{FS: fsys, Dir: "views", Layout: "layout.html"})
+		So(r.Load(), ShouldBeNil)
+
+		buf := new(bytes.Buffer)
+		So(r.Render(buf, "page", map[string]string{"name": "baa"}), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "[baa!]")
+
+		buf.Reset()
+		So(r.Render(buf, "sub/page.html", nil), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "[sub]")
+
+		So(r.Render(buf, "../page.html", nil), ShouldNotBeNil)
+		So(func() { NewRender(RenderOptions{FS: fsys, Dir: "../views"}) }, ShouldPanic)
+	})
+}
+
+func TestRender4(t *testing.T) {
+	Convey("strip blank lines", t, func() {
+		views := fstest.MapFS{
+			"pre.html":   {Data: []byte("<pre>\r\na\n\n  \nb</pre>\n{{ if .x }}\n{{ end }}\nend")},
+			"error.html": {Data: []byte("begin {{ .name.x }}")},
+			"large.html": {Data: []byte("{{ .large }}{{ .name.x }}")},
+		}
+		b := New()
+		b.SetDI("render", NewRender(RenderOptions{FS: views}))
+		b.Get("/:tpl", func(c *Context) {
+			c.Set("name", "baa")
+			c.Set("large", strings.Repeat("a", renderBufferSize))
+			c.HTML(200, c.Param("tpl")+".html")
+		})
+		w := serveRequest(b, "GET", "/pre")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "<pre>\na\nb</pre>\nend\n")
+		So(serveRequest(b, "GET", "/error").Code, ShouldEqual, http.StatusInternalServerError)
+
+		b.SetStripBlankLines(false)
+		w = serveRequest(b, "GET", "/pre")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get("Content-Type"), ShouldEqual, TextHTMLCharsetUTF8)
+		So(w.Body.String(), ShouldEqual, "<pre>\r\na\n\n  \nb</pre>\n\nend")
+
+		// the error before output is sent is handled by the error handler
+		So(serveRequest(b, "GET", "/error").Code, ShouldEqual, http.StatusInternalServerError)
+		So(serveRequest(b, "GET", "/large").Code, ShouldEqual, http.StatusInternalServerError)
+
+		// the error is logged after output started
+		b.SetDebug(false)
+		So(serveRequest(b, "GET", "/error").Code, ShouldEqual, http.StatusInternalServerError)
+		w = serveRequest(b, "GET", "/large")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldStartWith, strings.Repeat("a", renderBufferSize))
+	})
+}
@@ -67,9 +67,16 @@ func (r *Response) WriteHeader(code int) {
 }
 
 // Flush implements the http.Flusher interface to allow an HTTP handler to flush
-// buffered data to the client.
+// buffered data to the client, a buffered writer set by SetWriter is flushed first.
 // See [http.Flusher](https://golang.org/pkg/net/http/#Flusher)
 func (r *Response) Flush() {
+	switch v := r.writer.(type) {
+	case http.ResponseWriter:
+	case http.Flusher:
+		v.Flush()
+	case interface{ Flush() error }:
+		v.Flush()
+	}
 	if v, ok := r.resp.(http.Flusher); ok {
 		v.Flush()
 	}
@@ -12,7 +12,14 @@ const (
 	RouteLength
 )
 
-// RouterMethods declare method key in route table
+// AnyMethod is the method of routes match requests of every method,
+// they are matched only when the method of request has no matched route.
+// It is not a valid method token, so it is never the method of a request,
+// unlike "*" of Route which means all methods of RouterMethods.
+const AnyMethod = "<ANY>"
+
+// RouterMethods declare method key in route table,
+// routes of other methods (eg: PROPFIND, PURGE) are stored in a dynamic table.
 var RouterMethods = map[string]int{
 	"GET":     GET,
 	"POST":    POST,
@@ -55,6 +62,16 @@ type Router interface {
 	NamedRoutes() map[string]string
 }
 
+// MethodRouter is an optional interface of Router, baa uses it to answer
+// 405 Method Not Allowed with Allow header and OPTIONS automatically.
+type MethodRouter interface {
+	// SetAutoOptions sets the value who determines whether answer OPTIONS method
+	// automatically for a registered route without OPTIONS handler.
+	SetAutoOptions(v bool)
+	// Allowed returns the methods which have a route matched uri
+	Allowed(uri string) []string
+}
+
 // RouteNode is an router node
 type RouteNode interface {
 	Name(name string)
@@ -0,0 +1,136 @@
+package baa
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSE is a Server-Sent Events writer
+type SSE struct {
+	c   *Context
+	ctx context.Context // context of the request, c may be reused after the handler returns
+	mu  sync.Mutex
+}
+
+// SSE starts a Server-Sent Events response and returns the event writer,
+// response buffering of proxies and writers is disabled by flushing every event.
+func (c *Context) SSE() *SSE {
+	header := c.Resp.Header()
+	header.Set("Content-Type", TextEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	c.Resp.WriteHeader(200)
+	c.Resp.Flush()
+	return &SSE{c: c, ctx: c.Req.Context()}
+}
+
+// LastEventID returns the Last-Event-ID header sent by a reconnecting client
+func (s *SSE) LastEventID() string {
+	return s.c.Req.Header.Get("Last-Event-ID")
+}
+
+// Done returns a channel closed when the client is gone
+func (s *SSE) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes an event, event and id are omitted when empty.
+// data can be string or []byte, other values are encoded as JSON,
+// multiline data is sent as multiple data lines.
+func (s *SSE) Send(event, id string, data interface{}) error {
+	var text string
+	switch v := data.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		re, err := s.c.baa.Codec(ApplicationJSON).Marshal(v)
+		if err != nil {
+			return err
+		}
+		text = string(re)
+	}
+
+	var buf strings.Builder
+	if event != "" {
+		buf.WriteString("event: " + sseField(event) + "\n")
+	}
+	if id != "" {
+		buf.WriteString("id: " + sseField(id) + "\n")
+	}
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+	return s.write(buf.String())
+}
+
+// Retry tells the client the reconnection time
+func (s *SSE) Retry(d time.Duration) error {
+	return s.write("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n")
+}
+
+// Comment writes a comment line, clients ignore it
+func (s *SSE) Comment(text string) error {
+	return s.write(": " + sseField(text) + "\n\n")
+}
+
+// Heartbeat writes a comment every interval to keep the connection alive,
+// it stops when the client is gone, the returned stop func is called or the request is served.
+func (s *SSE) Heartbeat(interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			case <-quit:
+				return
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+		})
+	}
+	// the heartbeat never writes to the response after the handler returns
+	s.c.onRelease(stop)
+	return stop
+}
+
+// write writes text and flushes, it fails when the client is gone
+func (s *SSE) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.c.Resp.Write([]byte(text)); err != nil {
+		return err
+	}
+	s.c.Resp.Flush()
+	return nil
+}
+
+// sseField removes line breaks from a single line field
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,116 @@
+package baa
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testBufferWriter struct {
+	strings.Builder
+	buf     []byte
+	flushed bool
+}
+
+func (w *testBufferWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+func (w *testBufferWriter) Flush() error {
+	w.Builder.Write(w.buf)
+	w.buf = w.buf[:0]
+	w.flushed = true
+	return nil
+}
+
+func TestSSE1(t *testing.T) {
+	Convey("send events", t, func() {
+		b := New()
+		b.Get("/events", func(c *Context) {
+			sse := c.SSE()
+			So(sse.Retry(3*time.Second), ShouldBeNil)
+			So(sse.Send("", "", "hello"), ShouldBeNil)
+			So(sse.Send("update", sse.LastEventID()+"1", "line1\nline2"), ShouldBeNil)
+			So(sse.Send("json", "", map[string]int{"n": 1}), ShouldBeNil)
+			So(sse.Comment("ping\n"), ShouldBeNil)
+		})
+		req, _ := http.NewRequest("GET", "/events", nil)
+		req.Header.Set("Last-Event-ID", "4")
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Flushed, ShouldBeTrue)
+		So(w.Header().Get("Content-Type"), ShouldEqual, TextEventStream)
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "no-cache")
+		So(w.Header().Get("X-Accel-Buffering"), ShouldEqual, "no")
+		So(w.Body.String(), ShouldEqual, "retry: 3000\n\n"+
+			"data: hello\n\n"+
+			"event: update\nid: 41\ndata: line1\ndata: line2\n\n"+
+			"event: json\ndata: {\"n\":1}\n\n"+
+			": ping\n\n")
+	})
+
+	Convey("flush buffered writer", t, func() {
+		b := New()
+		bw := new(testBufferWriter)
+		b.Get("/events", func(c *Context) {
+			c.Resp.SetWriter(bw)
+			c.SSE().Send("", "", "hello")
+		})
+		req, _ := http.NewRequest("GET", "/events", nil)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(bw.flushed, ShouldBeTrue)
+		So(bw.String(), ShouldEqual, "data: hello\n\n")
+	})
+
+	Convey("stop when client is gone", t, func() {
+		b := New()
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		b.Get("/events", func(c *Context) {
+			sse := c.SSE()
+			stop := sse.Heartbeat(10 * time.Millisecond)
+			defer stop()
+			time.Sleep(35 * time.Millisecond)
+			cancel()
+			<-sse.Done()
+			result <- sse.Send("", "", "late")
+		})
+		req, _ := http.NewRequest("GET", "/events", nil)
+		req = req.WithContext(ctx)
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(<-result, ShouldEqual, context.Canceled)
+
+		s := bufio.NewScanner(strings.NewReader(w.Body.String()))
+		var beats int
+		for s.Scan() {
+			if s.Text() == ": heartbeat" {
+				beats++
+			}
+		}
+		So(beats, ShouldBeGreaterThanOrEqualTo, 1)
+		So(w.Body.String(), ShouldNotContainSubstring, "late")
+	})
+
+	Convey("stop when the request is served", t, func() {
+		b := New()
+		b.Get("/events", func(c *Context) {
+			c.SSE().Heartbeat(5 * time.Millisecond)
+			time.Sleep(12 * time.Millisecond)
+		})
+		w := serveRequest(b, "GET", "/events")
+		body := w.Body.String()
+		So(body, ShouldContainSubstring, ": heartbeat")
+		time.Sleep(20 * time.Millisecond)
+		So(w.Body.String(), ShouldEqual, body)
+	})
+}
@@ -1,54 +1,105 @@
 package baa
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // compatible with go net standard indexPage
 const indexPage = "/index.html"
 
+// StaticOptions is the options of static file serve
+type StaticOptions struct {
+	// Dir is the directory of files, it is used when FS is nil.
+	Dir string
+	// FS is the file system of files, such as embed.FS.
+	FS fs.FS
+	// Index lists files of directory, otherwise index.html of directory is served.
+	Index bool
+	// Handler is called before serving, it can be used for set headers.
+	Handler HandlerFunc
+	// Precompressed serves file.br or file.gz instead of file if the client accepts it.
+	Precompressed bool
+	// CacheControl is the Cache-Control header of files, such as "public, max-age=3600".
+	CacheControl string
+	// Immutable caches files for one year as immutable when the ?v= query equals the fingerprint
+	// of file content, such as the path returned by {{ static "/assets/app.js" }}.
+	Immutable bool
+	// ETag sets strong ETag by the hash of file content.
+	ETag bool
+}
+
+// immutableCacheControl is the Cache-Control of fingerprinted files
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
 // Static provider static file serve for baa.
 type static struct {
-	handler HandlerFunc
-	prefix  string
-	dir     string
-	index   bool
+	opt    StaticOptions
+	prefix string
+	fsys   fs.FS
+	etags  sync.Map
 }
 
-// newStatic returns a route handler with static file serve
-func newStatic(prefix, dir string, index bool, h HandlerFunc) HandlerFunc {
-	if len(prefix) > 1 && prefix[len(prefix)-1] == '/' {
-		prefix = prefix[:len(prefix)-1]
+// etag is the cached ETag of file
+type etag struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// fs returns the file system of options
+func (opt *StaticOptions) fs() fs.FS {
+	if opt.FS != nil {
+		return opt.FS
 	}
+	return staticDir(opt.Dir)
+}
+
+// staticDir returns the file system of static dir
+func staticDir(dir string) fs.FS {
 	if len(dir) > 1 && dir[len(dir)-1] == '/' {
 		dir = dir[:len(dir)-1]
 	}
+	return os.DirFS(dir)
+}
+
+// newStatic returns a route handler with static file serve
+func newStatic(prefix string, fsys fs.FS, opt StaticOptions) HandlerFunc {
+	if len(prefix) > 1 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
 	s := &static{
-		dir:     dir,
-		index:   index,
-		prefix:  prefix,
-		handler: h,
+		opt:    opt,
+		prefix: prefix,
+		fsys:   fsys,
 	}
 
 	return func(c *Context) {
-		file := c.Param("")
-		if len(file) > 0 && file[0] == '/' {
-			file = file[1:]
-		}
-		file = s.dir + "/" + file
+		file := cleanFSPath(c.Param(""))
 
-		if s.handler != nil {
-			s.handler(c)
+		if s.opt.Handler != nil {
+			s.opt.Handler(c)
 		}
 
 		// directory index
-		if f, err := os.Stat(file); err == nil {
+		if f, err := fs.Stat(s.fsys, file); err == nil {
 			if f.IsDir() {
-				if s.index {
+				if s.opt.Index {
 					// if no end slash, add slah and redriect
 					if c.Req.URL.Path[len(c.Req.URL.Path)-1] != '/' {
 						c.Redirect(302, c.Req.URL.Path+"/")
@@ -57,7 +108,7 @@ func newStatic(prefix, dir string, index bool, h HandlerFunc) HandlerFunc {
 					listDir(file, s, c)
 				} else {
 					// check index
-					if err := serveFile(file+indexPage, c); err != nil {
+					if err := s.serve(path.Join(file, indexPage[1:]), c); err != nil {
 						c.Resp.WriteHeader(http.StatusForbidden)
 					}
 				}
@@ -65,30 +116,169 @@ func newStatic(prefix, dir string, index bool, h HandlerFunc) HandlerFunc {
 			}
 		}
 
-		if len(file) >= len(indexPage) && file[len(file)-len(indexPage):] == indexPage {
-			if err := serveFile(file, c); err != nil {
-				c.Error(err)
+		if err := s.serve(file, c); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.NotFound()
+				return
 			}
-		} else {
-			http.ServeFile(c.Resp, c.Req, file)
+			c.Error(err)
 		}
 	}
 }
 
-// listDir list given dir files
-func listDir(dir string, s *static, c *Context) {
-	f, err := os.Open(dir)
+// serve serves file with precompressed variants and cache headers
+func (s *static) serve(file string, c *Context) error {
+	if !s.opt.Precompressed && !s.opt.ETag && s.opt.CacheControl == "" && !s.opt.Immutable {
+		return serveFile(s.fsys, file, c)
+	}
+
+	fi, err := fs.Stat(s.fsys, file)
 	if err != nil {
-		c.baa.Error(fmt.Errorf("baa.Static listDir Error: %s", err), c)
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("given path is dir, not file")
+	}
+
+	header := c.Resp.Header()
+	if header.Get("Cache-Control") == "" {
+		immutable, err := s.fingerprinted(file, c)
+		if err != nil {
+			return err
+		}
+		if immutable {
+			header.Set("Cache-Control", immutableCacheControl)
+		} else if s.opt.CacheControl != "" {
+			header.Set("Cache-Control", s.opt.CacheControl)
+		}
+	}
+
+	name := file
+	if s.opt.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if encoding, ext := s.encoding(file, c); encoding != "" {
+			ctype := mime.TypeByExtension(path.Ext(file))
+			if ctype == "" {
+				ctype = "application/octet-stream"
+			}
+			header.Set("Content-Type", ctype)
+			header.Set("Content-Encoding", encoding)
+			name = file + ext
+		}
+	}
+
+	if s.opt.ETag {
+		tag, err := s.etag(name)
+		if err != nil {
+			return err
+		}
+		header.Set("ETag", tag)
+	}
+	return serveFile(s.fsys, name, c)
+}
+
+// encoding returns the precompressed encoding and file extension accepted by client
+func (s *static) encoding(file string, c *Context) (string, string) {
+	accept := c.Req.Header.Get("Accept-Encoding")
+	for _, v := range [][2]string{{"br", ".br"}, {"gzip", ".gz"}} {
+		if !acceptsEncoding(accept, v[0]) {
+			continue
+		}
+		if fi, err := fs.Stat(s.fsys, file+v[1]); err == nil && !fi.IsDir() {
+			return v[0], v[1]
+		}
+	}
+	return "", ""
+}
+
+// etag returns strong ETag of file, it is cached until the file changes
+func (s *static) etag(file string) (string, error) {
+	fi, err := fs.Stat(s.fsys, file)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := s.etags.Load(file); ok {
+		e := v.(*etag)
+		if e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+			return e.value, nil
+		}
+	}
+	f, err := s.fsys.Open(file)
+	if err != nil {
+		return "", err
 	}
 	defer f.Close()
-	fl, err := f.Readdir(-1)
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	value := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(file, &etag{modTime: fi.ModTime(), size: fi.Size(), value: value})
+	return value, nil
+}
+
+// acceptsEncoding reports whether Accept-Encoding header accepts encoding
+func acceptsEncoding(accept, encoding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		name := strings.TrimSpace(params[0])
+		if name != encoding && name != "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name == encoding {
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+	return wildcard
+}
+
+// fingerprinted reports whether the ?v= query of request is the fingerprint of file content,
+// it is always false unless Immutable is enabled.
+func (s *static) fingerprinted(file string, c *Context) (bool, error) {
+	v := c.Req.URL.Query().Get("v")
+	if !s.opt.Immutable || v == "" {
+		return false, nil
+	}
+	// fingerprint is the prefix of ETag, both are the sha256 of file content
+	tag, err := s.etag(file)
+	if err != nil {
+		return false, err
+	}
+	return len(v) == 8 && tag[1:9] == v, nil
+}
+
+// cleanFSPath returns the valid fs.FS path of request file
+func cleanFSPath(file string) string {
+	file = path.Clean("/" + file)[1:]
+	if file == "" {
+		return "."
+	}
+	return file
+}
+
+// listDir list given dir files
+func listDir(dir string, s *static, c *Context) {
+	fl, err := fs.ReadDir(s.fsys, dir)
 	if err != nil {
 		c.baa.Error(fmt.Errorf("baa.Static listDir Error: %s", err), c)
+		return
 	}
 
-	dirName := f.Name()
-	dirName = dirName[len(s.dir):]
+	dirName := "/"
+	if dir != "." {
+		dirName += dir
+	}
 	c.Resp.Header().Add("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(c.Resp, "<h3 style=\"padding-bottom:5px;border-bottom:1px solid #ccc;\">%s</h3>\n", dirName)
 	fmt.Fprintf(c.Resp, "<pre>\n")
@@ -109,19 +299,28 @@ func listDir(dir string, s *static, c *Context) {
 	fmt.Fprintf(c.Resp, "</pre>\n")
 }
 
-func serveFile(file string, c *Context) error {
-	f, err := os.Open(file)
+// serveFile serves file in fsys, Range and If-Modified-Since requests are supported
+func serveFile(fsys fs.FS, file string, c *Context) error {
+	f, err := fsys.Open(file)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	fs, err := f.Stat()
+	fi, err := f.Stat()
 	if err != nil {
 		return err
 	}
-	if fs.IsDir() {
+	if fi.IsDir() {
 		return fmt.Errorf("given path is dir, not file")
 	}
-	http.ServeContent(c.Resp, c.Req, f.Name(), fs.ModTime(), f)
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(c.Resp, c.Req, fi.Name(), fi.ModTime(), content)
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -68,3 +69,129 @@ func TestStaticServe(t *testing.T) {
 		So(w.Code, ShouldEqual, http.StatusNotFound)
 	})
 }
+
+func TestStaticFS(t *testing.T) {
+	Convey("static serve from fs.FS", t, func() {
+		fsys := fstest.MapFS{
+			"index.html":     {Data: []byte("index")},
+			"css/style.css":  {Data: []byte("body{}")},
+			"docs/readme.md": {Data: []byte("readme")},
+		}
+		b := New()
+		b.StaticFS("/assets", fsys, false, nil)
+		b.StaticFS("/files/", fsys, true, nil)
+		b.StaticFileFS("/robots.txt", fsys, "robots.txt")
+		b.StaticFileFS("/style.css", fsys, "css/style.css")
+		g := b.NewGroup("/v1")
+		g.StaticFS("/assets", fsys, false, nil)
+		So(func() { b.StaticFS("", fsys, false, nil) }, ShouldPanic)
+		So(func() { b.StaticFS("/x", nil, false, nil) }, ShouldPanic)
+
+		w := serveRequest(b, "GET", "/assets/css/style.css")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "body{}")
+		So(w.Header().Get("Content-Type"), ShouldStartWith, "text/css")
+		So(serveRequest(b, "GET", "/assets/").Body.String(), ShouldEqual, "index")
+		So(serveRequest(b, "GET", "/assets/docs").Code, ShouldEqual, http.StatusForbidden)
+		So(serveRequest(b, "GET", "/assets/missing.css").Code, ShouldEqual, http.StatusNotFound)
+		So(serveRequest(b, "GET", "/assets/../index.html").Code, ShouldNotEqual, http.StatusInternalServerError)
+		So(serveRequest(b, "GET", "/v1/assets/css/style.css").Body.String(), ShouldEqual, "body{}")
+
+		So(serveRequest(b, "GET", "/files/docs").Code, ShouldEqual, http.StatusFound)
+		w = serveRequest(b, "GET", "/files/docs/")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldContainSubstring, "/docs</h3>")
+		So(w.Body.String(), ShouldContainSubstring, "readme.md")
+
+		So(serveRequest(b, "GET", "/style.css").Body.String(), ShouldEqual, "body{}")
+		So(serveRequest(b, "GET", "/robots.txt").Code, ShouldEqual, http.StatusNotFound)
+	})
+}
+
+func TestStaticWithOptions(t *testing.T) {
+	Convey("static serve with options", t, func() {
+		fsys := fstest.MapFS{
+			"app.js":             {Data: []byte("var app = 1;")},
+			"app.js.br":          {Data: []byte("br-data")},
+			"app.js.gz":          {Data: []byte("gz-data")},
+			"style.css":          {Data: []byte("body{}")},
+			"main.3f2a9c1d.js":   {Data: []byte("var main = 1;")},
+			"docs/index.html":    {Data: []byte("docs")},
+			"docs/index.html.gz": {Data: []byte("docs-gz")},
+		}
+		b := New()
+		b.StaticWithOptions("/assets", StaticOptions{
+			FS:            fsys,
+			Precompressed: true,
+			CacheControl:  "public, max-age=3600",
+			ETag:          true,
+		})
+		b.StaticWithOptions("/plain", StaticOptions{FS: fsys})
+		b.StaticWithOptions("/immutable", StaticOptions{FS: fsys, CacheControl: "public, max-age=3600", Immutable: true})
+		b.StaticWithOptions("/custom", StaticOptions{FS: fsys, CacheControl: "no-cache", Handler: func(c *Context) {
+			c.Resp.Header().Set("Cache-Control", "private")
+		}})
+		b.NewGroup("/v1").StaticWithOptions("/assets", StaticOptions{Dir: "./_fixture", CacheControl: "no-cache"})
+		So(func() { b.StaticWithOptions("", StaticOptions{FS: fsys}) }, ShouldPanic)
+		So(func() { b.StaticWithOptions("/x", StaticOptions{}) }, ShouldPanic)
+
+		w := serveRequest(b, "GET", "/assets/app.js", "Accept-Encoding", "gzip, deflate, br")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "br-data")
+		So(w.Header().Get("Content-Encoding"), ShouldEqual, "br")
+		So(w.Header().Get("Content-Type"), ShouldStartWith, "text/javascript")
+		So(w.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		brTag := w.Header().Get("ETag")
+		So(brTag, ShouldStartWith, `"`)
+
+		w = serveRequest(b, "GET", "/assets/app.js", "Accept-Encoding", "gzip, br;q=0")
+		So(w.Body.String(), ShouldEqual, "gz-data")
+		So(w.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+		So(w.Header().Get("ETag"), ShouldNotEqual, brTag)
+
+		w = serveRequest(b, "GET", "/assets/app.js")
+		So(w.Body.String(), ShouldEqual, "var app = 1;")
+		So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+		tag := w.Header().Get("ETag")
+		So(tag, ShouldNotBeEmpty)
+
+		w = serveRequest(b, "GET", "/assets/app.js", "If-None-Match", tag)
+		So(w.Code, ShouldEqual, http.StatusNotModified)
+
+		w = serveRequest(b, "GET", "/assets/docs/", "Accept-Encoding", "gzip")
+		So(w.Body.String(), ShouldEqual, "docs-gz")
+		So(w.Header().Get("Content-Type"), ShouldStartWith, "text/html")
+
+		// only the content fingerprint in ?v= is cached as immutable, and only if it is enabled
+		v := fingerprint(fsys, "style.css")
+		w = serveRequest(b, "GET", "/immutable/style.css?v="+v)
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=31536000, immutable")
+		So(w.Body.String(), ShouldEqual, "body{}")
+		w = serveRequest(b, "GET", "/immutable/style.css?v=1234abcd")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		w = serveRequest(b, "GET", "/assets/style.css?v="+v)
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		w = serveRequest(b, "GET", "/assets/main.3f2a9c1d.js")
+		So(w.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		w = serveRequest(b, "GET", "/plain/style.css?v="+v)
+		So(w.Header().Get("Cache-Control"), ShouldBeEmpty)
+		w = serveRequest(b, "GET", "/plain/style.css")
+		So(w.Header().Get("Cache-Control"), ShouldBeEmpty)
+		So(w.Header().Get("ETag"), ShouldBeEmpty)
+
+		So(serveRequest(b, "GET", "/custom/style.css").Header().Get("Cache-Control"), ShouldEqual, "private")
+		So(serveRequest(b, "GET", "/assets/missing.js").Code, ShouldEqual, http.StatusNotFound)
+		So(serveRequest(b, "GET", "/v1/assets/index1.html").Header().Get("Cache-Control"), ShouldEqual, "no-cache")
+	})
+
+	Convey("accept encoding", t, func() {
+		So(acceptsEncoding("gzip, br", "br"), ShouldBeTrue)
+		So(acceptsEncoding("gzip;q=0.5", "gzip"), ShouldBeTrue)
+		So(acceptsEncoding("gzip;q=0", "gzip"), ShouldBeFalse)
+		So(acceptsEncoding("*", "br"), ShouldBeTrue)
+		So(acceptsEncoding("*;q=0, gzip", "gzip"), ShouldBeTrue)
+		So(acceptsEncoding("br;q=0, *", "br"), ShouldBeFalse)
+		So(acceptsEncoding("", "gzip"), ShouldBeFalse)
+	})
+}
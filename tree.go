@@ -2,6 +2,10 @@ package baa
 
 import (
 	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -14,10 +18,13 @@ const (
 // Tree provlider router for baa with radix tree
 type Tree struct {
 	autoHead          bool
+	autoOptions       bool
 	autoTrailingSlash bool
 	mu                sync.RWMutex
 	groups            []*group
 	nodes             [RouteLength]*leaf
+	methodNodes       map[string]*leaf  // route table of methods not in RouterMethods
+	shapes            map[string]string // route pattern by method and pattern without param names
 	baa               *Baa
 	nameNodes         map[string]*Node
 }
@@ -33,16 +40,28 @@ type Node struct {
 
 // Leaf is a tree node
 type leaf struct {
-	kind        uint
-	pattern     string
-	param       string
-	handlers    []HandlerFunc
-	children    []*leaf
-	childrenNum uint
-	paramChild  *leaf
-	wideChild   *leaf
-	root        *Tree
-	nameNode    *Node
+	kind          uint
+	pattern       string
+	param         string
+	constraint    string            // param constraint, eg: int, [a-z]+
+	check         func(string) bool // param constraint checker
+	handlers      []HandlerFunc
+	children      []*leaf
+	childrenNum   uint
+	paramChildren []*leaf // constrained params first
+	wideChild     *leaf
+	root          *Tree
+	nameNode      *Node
+}
+
+// paramConstraints builtin param constraint checkers
+var paramConstraints = map[string]func(string) bool{
+	"int":   isIntParam,
+	"uint":  isUintParam,
+	"alpha": isAlphaParam,
+	"alnum": isAlnumParam,
+	"hex":   isHexParam,
+	"uuid":  isUUIDParam,
 }
 
 // group route
@@ -57,6 +76,8 @@ func NewTree(b *Baa) Router {
 	for i := 0; i < len(t.nodes); i++ {
 		t.nodes[i] = newLeaf("/", nil, t)
 	}
+	t.methodNodes = make(map[string]*leaf)
+	t.shapes = make(map[string]string)
 	t.nameNodes = make(map[string]*Node)
 	t.groups = make([]*group, 0)
 	t.baa = b
@@ -95,6 +116,12 @@ func (t *Tree) SetAutoHead(v bool) {
 	t.autoHead = v
 }
 
+// SetAutoOptions sets the value who determines whether answer OPTIONS method
+// automatically for a registered route without OPTIONS handler.
+func (t *Tree) SetAutoOptions(v bool) {
+	t.autoOptions = v
+}
+
 // SetAutoTrailingSlash optional trailing slash.
 func (t *Tree) SetAutoTrailingSlash(v bool) {
 	t.autoTrailingSlash = v
@@ -102,88 +129,97 @@ func (t *Tree) SetAutoTrailingSlash(v bool) {
 
 // Match find matched route then returns handlers and name
 func (t *Tree) Match(method, pattern string, c *Context) ([]HandlerFunc, string) {
-	var i, l int
-	var root, nl *leaf
-	root = t.nodes[RouterMethods[method]]
-	current := root
-
-	for {
-		switch current.kind {
-		case leafKindStatic:
-			// static route
-			l = len(current.pattern)
-			if l > len(pattern) {
-				break
-			}
-			i := l - 1
-			for ; i >= 0; i-- {
-				if current.pattern[i] != pattern[i] {
-					break
-				}
-			}
-			if i >= 0 {
-				break
-			}
-			if len(pattern) == l || current.children[pattern[l]] != nil ||
-				current.paramChild != nil ||
-				current.wideChild != nil {
-				pattern = pattern[l:]
-				root = current
-			}
-		case leafKindParam:
-			// params route
-			l = len(pattern)
-			for i = 0; i < l && pattern[i] != '/'; i++ {
-			}
-			if len(pattern[:i]) == 0 {
-				return nil, "" // param is empty
-			}
-			c.SetParam(current.param, pattern[:i])
-			pattern = pattern[i:]
-			root = current
-		case leafKindWide:
-			// wide route
-			c.SetParam(current.param, pattern)
-			pattern = pattern[:0]
-		default:
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var h []HandlerFunc
+	var name string
+	if root := t.root(method); root != nil {
+		h, name = t.match(root, pattern, c)
+	}
+	if h == nil && method != AnyMethod {
+		if root := t.methodNodes[AnyMethod]; root != nil {
+			h, name = t.match(root, pattern, c)
+		}
+	}
+	if h == nil && method == "OPTIONS" && t.autoOptions {
+		if methods := t.allowed(pattern); len(methods) > 0 {
+			return t.optionsHandlers(methods), ""
 		}
+	}
+	return h, name
+}
 
-		if len(pattern) == 0 {
-			if current.handlers != nil {
-				if current.nameNode != nil {
-					return current.handlers, current.nameNode.name
-				}
-				return current.handlers, ""
+// Allowed returns the methods which have a route matched uri
+func (t *Tree) Allowed(pattern string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.allowed(pattern)
+}
+
+// allowed returns the methods which have a route matched uri, t.mu must be held
+func (t *Tree) allowed(pattern string) []string {
+	var methods []string
+	for i := range t.nodes {
+		if h, _ := t.match(t.nodes[i], pattern, nil); h != nil {
+			methods = append(methods, RouterMethodName[i])
+		}
+	}
+	if len(t.methodNodes) > 0 {
+		var others []string
+		for method, root := range t.methodNodes {
+			if method == AnyMethod {
+				continue
 			}
-			if root.paramChild == nil && root.wideChild == nil {
-				return nil, ""
-			}
-		} else {
-			// children static route
-			if current == root {
-				if nl = root.children[pattern[0]]; nl != nil {
-					current = nl
-					continue
-				}
+			if h, _ := t.match(root, pattern, nil); h != nil {
+				others = append(others, method)
 			}
 		}
-
-		// param route
-		if root.paramChild != nil {
-			current = root.paramChild
-			continue
+		sort.Strings(others)
+		methods = append(methods, others...)
+	}
+	if t.autoOptions && len(methods) > 0 {
+		if h, _ := t.match(t.nodes[OPTIONS], pattern, nil); h == nil {
+			methods = append(methods, RouterMethodName[OPTIONS])
 		}
+	}
+	return methods
+}
 
-		// wide route
-		if root.wideChild != nil {
-			current = root.wideChild
-			continue
-		}
+// root returns the route table of method, returns nil if the method has no route
+func (t *Tree) root(method string) *leaf {
+	if i, ok := RouterMethods[method]; ok {
+		return t.nodes[i]
+	}
+	return t.methodNodes[method]
+}
 
-		break
+// optionsHandlers returns the handler chain answer OPTIONS with allowed methods,
+// the auto options handler of baa can add headers (eg: CORS) to the answer.
+func (t *Tree) optionsHandlers(methods []string) []HandlerFunc {
+	handlers := []HandlerFunc{
+		WrapHandlerFunc(func(c *Context) {
+			c.Resp.Header().Set("Allow", strings.Join(methods, ", "))
+		}),
+	}
+	if t.baa != nil && t.baa.optionsHandler != nil {
+		handlers = append(handlers, WrapHandlerFunc(t.baa.optionsHandler))
 	}
+	return append(handlers, func(c *Context) {
+		c.Resp.WriteHeader(http.StatusNoContent)
+	})
+}
 
-	return nil, ""
+// match find matched route in the given method root,
+// params will not be set when c is nil
+func (t *Tree) match(root *leaf, pattern string, c *Context) ([]HandlerFunc, string) {
+	l := root.match(pattern, c)
+	if l == nil {
+		return nil, ""
+	}
+	if l.nameNode != nil {
+		return l.handlers, l.nameNode.name
+	}
+	return l.handlers, ""
 }
 
 // URLFor use named route return format url
@@ -191,6 +227,8 @@ func (t *Tree) URLFor(name string, args ...interface{}) string {
 	if name == "" {
 		return ""
 	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	node := t.nameNodes[name]
 	if node == nil || len(node.format) == 0 {
 		return ""
@@ -205,6 +243,8 @@ func (t *Tree) URLFor(name string, args ...interface{}) string {
 
 // Routes returns registered route uri in a string slice
 func (t *Tree) Routes() map[string][]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	routes := make(map[string][]string)
 	for _, method := range RouterMethodName {
 		routes[method] = make([]string, 0)
@@ -212,6 +252,9 @@ func (t *Tree) Routes() map[string][]string {
 	for k := range t.nodes {
 		routes[RouterMethodName[k]] = t.routes(t.nodes[k])
 	}
+	for method, root := range t.methodNodes {
+		routes[method] = t.routes(root)
+	}
 
 	return routes
 }
@@ -225,14 +268,20 @@ func (t *Tree) routes(l *leaf) []string {
 	if l.handlers != nil {
 		data = append(data, l.String())
 	}
-	l.children = append(l.children, l.paramChild)
-	l.children = append(l.children, l.wideChild)
+	children := make([]*leaf, 0, int(l.childrenNum)+len(l.paramChildren)+1)
 	for i := range l.children {
 		if l.children[i] != nil {
-			cdata := t.routes(l.children[i])
-			for i := range cdata {
-				data = append(data, l.String()+cdata[i])
-			}
+			children = append(children, l.children[i])
+		}
+	}
+	children = append(children, l.paramChildren...)
+	if l.wideChild != nil {
+		children = append(children, l.wideChild)
+	}
+	for i := range children {
+		cdata := t.routes(children[i])
+		for i := range cdata {
+			data = append(data, l.String()+cdata[i])
 		}
 	}
 
@@ -241,6 +290,8 @@ func (t *Tree) routes(l *leaf) []string {
 
 // NamedRoutes returns named route uri in a string slice
 func (t *Tree) NamedRoutes() map[string]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	routes := make(map[string]string)
 	for k, v := range t.nameNodes {
 		routes[k] = v.pattern
@@ -284,8 +335,8 @@ func (t *Tree) GroupAdd(pattern string, f func(), handlers []HandlerFunc) {
 
 // add registers a new request handle with the given method, pattern and handlers.
 func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
-	if _, ok := RouterMethods[method]; !ok {
-		panic("unsupport http method [" + method + "]")
+	if method != AnyMethod && !isMethodToken(method) {
+		panic("invalid http method [" + method + "]")
 	}
 
 	t.mu.Lock()
@@ -314,11 +365,22 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 		panic("route pattern must begin /")
 	}
 
+	// routes differ only in param names can not be matched both
+	shape := method + " " + routeShape(pattern)
+	if p, ok := t.shapes[shape]; ok && p != pattern {
+		panic("Router Tree.insert error: route [" + pattern + "] conflicts with [" + p + "]")
+	}
+	t.shapes[shape] = pattern
+
 	for i := 0; i < len(handlers); i++ {
 		handlers[i] = WrapHandlerFunc(handlers[i])
 	}
 
-	root := t.nodes[RouterMethods[method]]
+	root := t.root(method)
+	if root == nil {
+		root = newLeaf("/", nil, t)
+		t.methodNodes[method] = root
+	}
 	origPattern := pattern
 	nameNode := NewNode(origPattern, t)
 
@@ -333,8 +395,7 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 	pattern = pattern[1:]
 
 	var radix []byte
-	var param []byte
-	var i, k int
+	var i int
 	var tl *leaf
 	for i = 0; i < len(pattern); i++ {
 		// wide route
@@ -359,29 +420,23 @@ func (t *Tree) add(method, pattern string, handlers []HandlerFunc) RouteNode {
 				radix = radix[:0]
 			}
 			// set param route
-			param = param[:0]
-			k = 0
-			for i = i + 1; i < len(pattern); i++ {
-				if pattern[i] == '/' {
-					i--
-					break
-				}
-				param = append(param, pattern[i])
-				k++
-			}
-			if k == 0 {
+			name, constraint, end := scanParam(pattern, i+1)
+			if len(name) == 0 {
 				panic("route pattern param is empty")
 			}
 			// check last character
-			if i == len(pattern) {
+			if end == len(pattern) {
 				tl = newLeaf(":", handlers, t)
 				tl.nameNode = nameNode
 			} else {
 				tl = newLeaf(":", nil, t)
 			}
-			tl.param = string(param[:k])
+			tl.param = name
+			tl.constraint = constraint
+			tl.check = newParamCheck(constraint)
 			tl.kind = leafKindParam
 			root = root.insertChild(tl)
+			i = end - 1
 			continue
 		}
 		radix = append(radix, pattern[i])
@@ -410,21 +465,37 @@ func (l *leaf) insertChild(node *leaf) *leaf {
 
 	// param route
 	if node.kind == leafKindParam {
-		if l.paramChild == nil {
-			l.paramChild = node
-			return l.paramChild
+		var same *leaf
+		for _, child := range l.paramChildren {
+			if child.constraint != node.constraint {
+				continue
+			}
+			// params with different name can share prefix, conflicts are checked by route shape
+			if child.param == node.param {
+				same = child
+			}
 		}
-		if l.paramChild.param != node.param {
-			panic("Router Tree.insert error cannot use two param [:" + l.paramChild.param + ", :" + node.param + "] with same prefix!")
+		if same != nil {
+			if node.handlers != nil {
+				if same.handlers != nil {
+					panic("Router Tree.insert error: cannot twice set handler for same route")
+				}
+				same.handlers = node.handlers
+				same.nameNode = node.nameNode
+			}
+			return same
 		}
-		if node.handlers != nil {
-			if l.paramChild.handlers != nil {
-				panic("Router Tree.insert error: cannot twice set handler for same route")
+		// constrained params are matched before the unconstrained one,
+		// params in same kind are matched by the order of registration
+		i := len(l.paramChildren)
+		if node.constraint != "" {
+			for i = 0; i < len(l.paramChildren) && l.paramChildren[i].constraint != ""; i++ {
 			}
-			l.paramChild.handlers = node.handlers
-			l.paramChild.nameNode = node.nameNode
 		}
-		return l.paramChild
+		l.paramChildren = append(l.paramChildren, nil)
+		copy(l.paramChildren[i+1:], l.paramChildren[i:])
+		l.paramChildren[i] = node
+		return node
 	}
 
 	// static route
@@ -460,7 +531,7 @@ func (l *leaf) insertChild(node *leaf) *leaf {
 	newChild.nameNode = child.nameNode
 	newChild.children = child.children
 	newChild.childrenNum = child.childrenNum
-	newChild.paramChild = child.paramChild
+	newChild.paramChildren = child.paramChildren
 	newChild.wideChild = child.wideChild
 
 	// node is prefix of child
@@ -487,13 +558,77 @@ func (l *leaf) reset(pattern string, handlers []HandlerFunc) {
 	l.pattern = pattern
 	l.children = make([]*leaf, 128)
 	l.childrenNum = 0
-	l.paramChild = nil
+	l.paramChildren = nil
 	l.wideChild = nil
 	l.nameNode = nil
 	l.param = ""
+	l.constraint = ""
+	l.check = nil
 	l.handlers = handlers
 }
 
+// match returns the leaf matched pattern, the priority is:
+// static > constrained param > param > wide,
+// it backtracks to the next candidate when a child failed in deeper,
+// params set by a failed leaf are removed from c.
+func (l *leaf) match(pattern string, c *Context) *leaf {
+	var n int
+	if c != nil {
+		n = len(c.pNames)
+	}
+
+	switch l.kind {
+	case leafKindStatic:
+		if len(l.pattern) > len(pattern) || pattern[:len(l.pattern)] != l.pattern {
+			return nil
+		}
+		pattern = pattern[len(l.pattern):]
+	case leafKindParam:
+		i := strings.IndexByte(pattern, '/')
+		if i < 0 {
+			i = len(pattern)
+		}
+		if i == 0 || (l.check != nil && !l.check(pattern[:i])) {
+			return nil
+		}
+		if c != nil {
+			c.SetParam(l.param, pattern[:i])
+		}
+		pattern = pattern[i:]
+	case leafKindWide:
+		if c != nil {
+			c.SetParam(l.param, pattern)
+		}
+		return l
+	}
+
+	if len(pattern) == 0 {
+		if l.handlers != nil {
+			return l
+		}
+	} else if int(pattern[0]) < len(l.children) {
+		if child := l.children[pattern[0]]; child != nil {
+			if m := child.match(pattern, c); m != nil {
+				return m
+			}
+		}
+	}
+	for _, child := range l.paramChildren {
+		if m := child.match(pattern, c); m != nil {
+			return m
+		}
+	}
+	if l.wideChild != nil {
+		return l.wideChild.match(pattern, c)
+	}
+
+	if c != nil {
+		c.pNames = c.pNames[:n]
+		c.pValues = c.pValues[:n]
+	}
+	return nil
+}
+
 // hasPrefixString returns the same prefix position, if none return 0
 func (l *leaf) hasPrefixString(s string) int {
 	var i, j int
@@ -511,6 +646,9 @@ func (l *leaf) String() string {
 	s := l.pattern
 	if l.kind == leafKindParam {
 		s += l.param
+		if l.constraint != "" {
+			s += "<" + l.constraint + ">"
+		}
 	}
 	return s
 }
@@ -520,25 +658,179 @@ func (n *Node) Name(name string) {
 	if name == "" {
 		return
 	}
+	n.root.mu.Lock()
+	defer n.root.mu.Unlock()
+	n.format, n.paramNum = formatPattern(n.pattern)
+	n.name = name
+	n.root.nameNodes[name] = n
+}
+
+// routeShape returns pattern without param names, eg: /files/:<int>/raw for /files/:id<int>/raw
+func routeShape(pattern string) string {
+	f := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ':' {
+			f = append(f, pattern[i])
+			continue
+		}
+		_, constraint, end := scanParam(pattern, i+1)
+		f = append(f, ':')
+		if constraint != "" {
+			f = append(f, '<')
+			f = append(f, constraint...)
+			f = append(f, '>')
+		}
+		i = end - 1
+	}
+	return string(f)
+}
+
+// formatPattern returns a fmt format of route pattern which params are replaced by %v,
+// and returns the number of params.
+func formatPattern(pattern string) (string, int) {
 	p := 0
-	f := make([]byte, 0, len(n.pattern))
-	for i := 0; i < len(n.pattern); i++ {
-		if n.pattern[i] != ':' {
-			f = append(f, n.pattern[i])
+	f := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ':' {
+			f = append(f, pattern[i])
 			continue
 		}
 		f = append(f, '%')
 		f = append(f, 'v')
 		p++
-		for i = i + 1; i < len(n.pattern); i++ {
-			if n.pattern[i] == '/' {
-				i--
-				break
+		_, _, end := scanParam(pattern, i+1)
+		i = end - 1
+	}
+	return string(f), p
+}
+
+// scanParam reads param name and constraint from pattern begin at i (after the colon),
+// returns the end position of param which is a slash or the end of pattern.
+// eg: /user/:id<int>/name, /file/:name<[a-z0-9-]+\.txt>
+func scanParam(pattern string, i int) (name, constraint string, end int) {
+	start := i
+	for ; i < len(pattern) && pattern[i] != '/' && pattern[i] != '<'; i++ {
+	}
+	name = pattern[start:i]
+	if i == len(pattern) || pattern[i] != '<' {
+		return name, "", i
+	}
+
+	start = i + 1
+	depth := 0
+	for ; i < len(pattern); i++ {
+		if pattern[i] == '<' {
+			depth++
+		} else if pattern[i] == '>' {
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if i == len(pattern) {
+		panic("route pattern param constraint of [:" + name + "] is not closed")
+	}
+	constraint = pattern[start:i]
+	if constraint == "" {
+		panic("route pattern param constraint of [:" + name + "] is empty")
+	}
+	i++
+	if i < len(pattern) && pattern[i] != '/' {
+		panic("route pattern param constraint of [:" + name + "] must end with slash")
+	}
+	return name, constraint, i
+}
+
+// newParamCheck returns the checker of param constraint,
+// constraint is a builtin type or a regular expression matched whole param.
+func newParamCheck(constraint string) func(string) bool {
+	if constraint == "" {
+		return nil
+	}
+	if f, ok := paramConstraints[constraint]; ok {
+		return f
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic("route pattern param constraint <" + constraint + "> is invalid: " + err.Error())
+	}
+	return re.MatchString
+}
+
+func isIntParam(s string) bool {
+	if len(s) > 1 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUintParam(s)
+}
+
+func isUintParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func isAlphaParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < 'A' || s[i] > 'Z') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func isAlnumParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < 'A' || s[i] > 'Z') && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func isHexParam(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') && (s[i] < 'A' || s[i] > 'F') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// isUUIDParam check the param is a uuid in 8-4-4-4-12 format
+func isUUIDParam(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHexParam(s[i : i+1]) {
+				return false
 			}
 		}
 	}
-	n.format = string(f)
-	n.paramNum = p
-	n.name = name
-	n.root.nameNodes[name] = n
+	return true
+}
+
+// isMethodToken check the method is a valid http method token
+func isMethodToken(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if c <= ' ' || c >= 127 || strings.IndexByte("\"(),/:;<=>?@[\\]{}", c) >= 0 {
+			return false
+		}
+	}
+	return true
 }
@@ -20,13 +20,17 @@ func (t *Tree) print(prefix string, root *leaf) {
 	}
 	prefix = fmt.Sprintf("%s -> %s", prefix, root.pattern)
 	fmt.Println(prefix)
-	root.children = append(root.children, root.paramChild)
-	root.children = append(root.children, root.wideChild)
 	for i := range root.children {
 		if root.children[i] != nil {
 			t.print(prefix, root.children[i])
 		}
 	}
+	for i := range root.paramChildren {
+		t.print(prefix, root.paramChildren[i])
+	}
+	if root.wideChild != nil {
+		t.print(prefix, root.wideChild)
+	}
 }
 
 func TestTreeRouteAdd1(t *testing.T) {
@@ -115,12 +119,12 @@ func TestTreeRouteAdd5(t *testing.T) {
 }
 
 func TestTreeRouteAdd6(t *testing.T) {
-	Convey("add route with not support method", t, func() {
+	Convey("add route with invalid method", t, func() {
 		defer func() {
 			e := recover()
 			So(e, ShouldNotBeNil)
 		}()
-		r.Add("TRACE", "/", []HandlerFunc{f})
+		r.Add("GE T", "/", []HandlerFunc{f})
 	})
 }
 
@@ -157,6 +161,50 @@ func TestTreeRouteAdd9(t *testing.T) {
 			b2.ServeHTTP(w, req)
 			So(w.Code, ShouldEqual, http.StatusOK)
 		})
+		Convey("set auto options route", func() {
+			b3 := New()
+			b3.Get("/options", f)
+			b3.Post("/options", f)
+			b3.Options("/options2", func(c *Context) {
+				c.String(200, "custom")
+			})
+			req, _ := http.NewRequest("OPTIONS", "/options", nil)
+			w := httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Header().Get("Allow"), ShouldEqual, "GET, POST")
+
+			b3.SetAutoOptions(true)
+			w = httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Header().Get("Allow"), ShouldEqual, "GET, POST, OPTIONS")
+
+			b3.SetAutoOptionsHandler(func(c *Context) {
+				c.Resp.Header().Set("Access-Control-Allow-Origin", "*")
+			})
+			w = httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "*")
+
+			req, _ = http.NewRequest("PUT", "/options", nil)
+			w = httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Header().Get("Allow"), ShouldEqual, "GET, POST, OPTIONS")
+
+			req, _ = http.NewRequest("OPTIONS", "/options2", nil)
+			w = httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "custom")
+
+			req, _ = http.NewRequest("OPTIONS", "/options3", nil)
+			w = httptest.NewRecorder()
+			b3.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
 		Convey("set auto training slash", func() {
 			b2.SetAutoTrailingSlash(true)
 			b2.Get("/slash", func(c *Context) {})
@@ -192,6 +240,11 @@ func TestTreeRouteAdd9(t *testing.T) {
 			b2.Route("/mul2", "GET,HEAD,POST", func(c *Context) {
 				c.String(200, "mul")
 			})
+			// * of Route means all methods of RouterMethods, not the AnyMethod fallback
+			So(b2.Router().Routes()["*"], ShouldBeEmpty)
+			So(b2.Router().Routes()[AnyMethod], ShouldNotContain, "/mul1")
+			So(b2.Router().Routes()["PUT"], ShouldContain, "/mul1")
+			So(func() { b2.Router().Add("<GET>", "/mul3", nil) }, ShouldPanic)
 			req, _ := http.NewRequest("HEAD", "/mul2", nil)
 			w := httptest.NewRecorder()
 			b2.ServeHTTP(w, req)
@@ -222,6 +275,40 @@ func TestTreeRouteAdd9(t *testing.T) {
 	})
 }
 
+func TestTreeRouteAdd10(t *testing.T) {
+	Convey("add route with extension method", t, func() {
+		b2 := New()
+		b2.Route("/dav/*", "PROPFIND,MKCOL", func(c *Context) {
+			c.String(207, c.Req.Method+" "+c.Param(""))
+		})
+		b2.Route("/cache/:key", "PURGE", func(c *Context) {
+			c.String(200, "purged "+c.Param("key"))
+		})
+		b2.Get("/cache/:key", f)
+
+		req, _ := http.NewRequest("PROPFIND", "/dav/a/b", nil)
+		w := httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, 207)
+		So(w.Body.String(), ShouldEqual, "PROPFIND a/b")
+
+		req, _ = http.NewRequest("PURGE", "/cache/home", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "purged home")
+
+		req, _ = http.NewRequest("REPORT", "/cache/home", nil)
+		w = httptest.NewRecorder()
+		b2.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(w.Header().Get("Allow"), ShouldEqual, "GET, PURGE")
+
+		routes := b2.Router().Routes()
+		So(routes["MKCOL"], ShouldResemble, []string{"/dav/*"})
+	})
+}
+
 func TestTreeRouteMatch1(t *testing.T) {
 	Convey("match route", t, func() {
 
@@ -279,6 +366,151 @@ func TestTreeRouteMatch2(t *testing.T) {
 	})
 }
 
+func TestTreeRouteMatch3(t *testing.T) {
+	Convey("match route with param constraints", t, func() {
+		b2 := New()
+		r2 := b2.Router()
+		b2.Get("/user/:id<int>", func(c *Context) {
+			c.String(200, "id "+c.Param("id"))
+		})
+		b2.Get("/user/me", func(c *Context) {
+			c.String(200, "me")
+		})
+		b2.Get("/user/:name", func(c *Context) {
+			c.String(200, "name "+c.Param("name"))
+		})
+		b2.Get("/post/:slug<[a-z0-9-]+>/show", f).Name("postShow")
+		b2.Get("/order/:uuid<uuid>", f)
+		b2.Get("/hex/:v<hex>/:w<alpha>", f)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/user/123", nil)
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "id 123")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/user/me", nil)
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "me")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/user/baa", nil)
+		b2.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "name baa")
+
+		c2 := NewContext(nil, nil, b2)
+		ru, _ := r2.Match("GET", "/post/hello-baa-2/show", c2)
+		So(ru, ShouldNotBeNil)
+		So(c2.Param("slug"), ShouldEqual, "hello-baa-2")
+		ru, _ = r2.Match("GET", "/post/Hello/show", c2)
+		So(ru, ShouldBeNil)
+		So(b2.URLFor("postShow", "hello"), ShouldEqual, "/post/hello/show")
+
+		ru, _ = r2.Match("GET", "/order/6ba7b810-9dad-11d1-80b4-00c04fd430c8", c2)
+		So(ru, ShouldNotBeNil)
+		ru, _ = r2.Match("GET", "/order/6ba7b810", c2)
+		So(ru, ShouldBeNil)
+		ru, _ = r2.Match("GET", "/hex/ff/abc", c2)
+		So(ru, ShouldNotBeNil)
+		ru, _ = r2.Match("GET", "/hex/fg/abc", c2)
+		So(ru, ShouldBeNil)
+
+		routes := r2.Routes()["GET"]
+		So(routes, ShouldContain, "/user/:id<int>")
+		So(routes, ShouldContain, "/post/:slug<[a-z0-9-]+>/show")
+
+		Convey("invalid constraint", func() {
+			defer func() {
+				e := recover()
+				So(e, ShouldNotBeNil)
+			}()
+			b2.Get("/bad/:id<[a-z>", f)
+		})
+		Convey("unclosed constraint", func() {
+			defer func() {
+				e := recover()
+				So(e, ShouldNotBeNil)
+			}()
+			b2.Get("/bad/:id<int", f)
+		})
+	})
+}
+
+func TestTreeRouteConcurrent(t *testing.T) {
+	Convey("add routes while matching", t, func() {
+		b := New()
+		r := b.Router()
+		c := NewContext(nil, nil, b)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				r.Add("PURGE", fmt.Sprintf("/cache/%d", i), []HandlerFunc{f})
+				r.Add("GET", fmt.Sprintf("/page/%d", i), []HandlerFunc{f}).Name(fmt.Sprintf("page%d", i))
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			r.Match("PURGE", "/cache/1", c)
+			r.Match("GET", "/page/1", c)
+			r.(MethodRouter).Allowed("/cache/1")
+			r.Routes()
+			r.URLFor("page1")
+		}
+		<-done
+		h, _ := r.Match("PURGE", "/cache/99", c)
+		So(h, ShouldNotBeNil)
+	})
+}
+
+func TestTreeRouteMatch4(t *testing.T) {
+	Convey("match route with priority and backtracking", t, func() {
+		b2 := New()
+		r2 := b2.Router()
+		c2 := NewContext(nil, nil, b2)
+		b2.Get("/files/new", func(c *Context) {})
+		b2.Get("/files/:id", func(c *Context) {})
+		b2.Get("/files/:id/raw", func(c *Context) {})
+		b2.Get("/files/:name/edit", func(c *Context) {})
+		b2.Get("/files/*", func(c *Context) {})
+		b2.Get("/src/new/:id/raw", func(c *Context) {})
+		b2.Get("/src/:name/tree", func(c *Context) {})
+
+		match := func(uri string) map[string]string {
+			c2.Reset(nil, nil)
+			if ru, _ := r2.Match("GET", uri, c2); ru == nil {
+				return nil
+			}
+			return c2.Params()
+		}
+
+		So(match("/files/new"), ShouldResemble, map[string]string{})
+		So(match("/files/123"), ShouldResemble, map[string]string{"id": "123"})
+		So(match("/files/new/raw"), ShouldResemble, map[string]string{"id": "new"})
+		So(match("/files/newer/raw"), ShouldResemble, map[string]string{"id": "newer"})
+		So(match("/files/123/edit"), ShouldResemble, map[string]string{"name": "123"})
+		So(match("/files/123/raw/x"), ShouldResemble, map[string]string{"": "123/raw/x"})
+		So(match("/src/new/tree"), ShouldResemble, map[string]string{"name": "new"})
+		So(match("/src/new/1/raw"), ShouldResemble, map[string]string{"id": "1"})
+		So(match("/src/new/1/tree"), ShouldBeNil)
+
+		Convey("conflict param route", func() {
+			defer func() {
+				e := recover()
+				So(e, ShouldNotBeNil)
+			}()
+			b2.Get("/files/:key", f)
+		})
+		Convey("conflict param route with same suffix", func() {
+			b3 := New()
+			b3.Get("/files/:id/raw", f)
+			So(func() { b3.Get("/files/:name/raw", f) }, ShouldPanic)
+			So(func() { b3.Get("/files/:name<int>/raw", f) }, ShouldNotPanic)
+			So(func() { b3.Get("/files/:id/raw", f) }, ShouldPanic)
+			So(func() { b3.Post("/files/:name/raw", f) }, ShouldNotPanic)
+		})
+	})
+}
+
 func _TestTreeRoutePrint1(t *testing.T) {
 	Convey("print route table", t, func() {
 		r.(*Tree).print("", nil)
@@ -0,0 +1,169 @@
+package baa
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrBodyTooLarge is returned when request body exceeds the body size limit,
+// it is treated as 413 Request Entity Too Large by the default error handler.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// SetMaxBodySize set max size of request body in bytes for all routes, 0 means no limit.
+// Reading more than the limit fails with ErrBodyTooLarge, routes can change it by BodyLimit.
+func (b *Baa) SetMaxBodySize(n int64) {
+	b.maxBodySize = n
+}
+
+// SetMaxMemory set max memory in bytes used when parsing a multipart form,
+// file parts over the memory are stored in temporary files. Default is 32 MB.
+func (b *Baa) SetMaxMemory(n int64) {
+	b.maxMemory = n
+}
+
+// BodyLimit returns a handler limits request body to n bytes,
+// it overrides the application limit and answers 413 when Content-Length exceeds n.
+func BodyLimit(n int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > n {
+			c.Error(ErrBodyTooLarge)
+			return
+		}
+		c.SetBodyLimit(n)
+		c.Next()
+	}
+}
+
+// BodyCache returns a handler caches request body up to max bytes,
+// so later handlers can read the body many times, larger body is answered with 413.
+func BodyCache(max int64) HandlerFunc {
+	return func(c *Context) {
+		if err := c.CacheBody(max); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetBodyLimit limits request body to n bytes, the limit of original body is replaced.
+// It should be called before the body is read.
+func (c *Context) SetBodyLimit(n int64) {
+	if c.rawBody == nil {
+		c.rawBody = c.Req.Body
+	}
+	if c.rawBody == nil {
+		return
+	}
+	c.Req.Body = &limitedBody{
+		ReadCloser: http.MaxBytesReader(c.Resp.resp, c.rawBody, n),
+		limit:      n,
+	}
+}
+
+// bodyTooLarge reports whether the body limit is exceeded
+func (c *Context) bodyTooLarge() bool {
+	lb, ok := c.Req.Body.(*limitedBody)
+	return ok && lb.exceeded
+}
+
+// limitedBody turns the error of http.MaxBytesReader to ErrBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+		err = ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// EachPart calls fn with every part of multipart request body in order,
+// parts are streamed from the body without buffering in memory or temporary files.
+// It stops at the first error returned by fn.
+func (c *Context) EachPart(fn func(part *multipart.Part) error) error {
+	c.rewindBody()
+	mr, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if c.bodyTooLarge() {
+				return ErrBodyTooLarge
+			}
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			if c.bodyTooLarge() {
+				return ErrBodyTooLarge
+			}
+			return err
+		}
+	}
+}
+
+// SaveFiles saves all files uploaded in field to dir and returns the saved paths.
+// namer returns the file name for every file, the base name of uploaded file name is used if namer is nil.
+// Directory part of names are dropped, so files cannot escape from dir.
+func (c *Context) SaveFiles(field, dir string, namer func(fh *multipart.FileHeader) string) ([]string, error) {
+	if err := c.ParseForm(0); err != nil {
+		return nil, err
+	}
+	if c.Req.MultipartForm == nil || len(c.Req.MultipartForm.File[field]) == 0 {
+		return nil, http.ErrMissingFile
+	}
+
+	var paths []string
+	for _, fh := range c.Req.MultipartForm.File[field] {
+		name := fh.Filename
+		if namer != nil {
+			name = namer(fh)
+		}
+		name = filepath.Base(filepath.Clean("/" + filepath.FromSlash(name)))
+		if name == string(filepath.Separator) || name == "." {
+			return paths, http.ErrMissingFile
+		}
+		path := filepath.Join(dir, name)
+		if err := saveFile(fh, path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// saveFile saves uploaded file to path
+func saveFile(fh *multipart.FileHeader, path string) error {
+	fr, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer fr.Close()
+
+	fw, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	_, err = io.Copy(fw, fr)
+	return err
+}
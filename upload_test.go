@@ -0,0 +1,142 @@
+package baa
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newMultipartRequest(uri string, files map[string][]string) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "baa")
+	for field, names := range files {
+		for _, name := range names {
+			fw, _ := mw.CreateFormFile(field, name)
+			fw.Write([]byte("content of " + name))
+		}
+	}
+	mw.Close()
+	req, _ := http.NewRequest("POST", uri, body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUpload1(t *testing.T) {
+	Convey("body size limit", t, func() {
+		b := New()
+		b.SetMaxBodySize(8)
+		handler := func(c *Context) {
+			s, err := c.Body().String()
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			c.String(200, s)
+		}
+		b.Post("/", handler)
+		b.Post("/large", BodyLimit(32), handler)
+		b.Post("/small", BodyLimit(4), handler)
+
+		serve := func(uri, body string, chunked bool) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", uri, strings.NewReader(body))
+			if chunked {
+				req.ContentLength = -1
+			}
+			return serveHTTP(b, req)
+		}
+
+		w := serve("/", "12345678", false)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "12345678")
+		So(serve("/", "123456789", false).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(serve("/", "123456789", true).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		w = serve("/large", "1234567890", false)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, "1234567890")
+
+		So(serve("/small", "12345", false).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(serve("/small", "12345", true).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	Convey("multipart form limit", t, func() {
+		b := New()
+		b.Post("/", BodyLimit(64), func(c *Context) {
+			c.Error(c.ParseForm(0))
+		})
+		req := newMultipartRequest("/", map[string][]string{"file": {"a.txt"}})
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	Convey("stream multipart parts", t, func() {
+		b := New()
+		b.SetMaxMemory(1)
+		b.Post("/", func(c *Context) {
+			var parts []string
+			err := c.EachPart(func(part *multipart.Part) error {
+				content, err := ioutil.ReadAll(part)
+				parts = append(parts, part.FormName()+"="+string(content))
+				return err
+			})
+			So(err, ShouldBeNil)
+			c.String(200, strings.Join(parts, ","))
+		})
+		req := newMultipartRequest("/", map[string][]string{"file": {"a.txt"}})
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, "title=baa,file=content of a.txt")
+
+		b.Post("/stop", func(c *Context) {
+			err := c.EachPart(func(part *multipart.Part) error {
+				return io.ErrUnexpectedEOF
+			})
+			So(err, ShouldEqual, io.ErrUnexpectedEOF)
+			So(c.EachPart(nil), ShouldNotBeNil)
+		})
+		req = newMultipartRequest("/stop", nil)
+		b.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	Convey("save files", t, func() {
+		dir, err := ioutil.TempDir("", "baa")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		b := New()
+		var paths []string
+		b.Post("/", func(c *Context) {
+			paths, err = c.SaveFiles("files", dir, nil)
+		})
+		b.Post("/named", func(c *Context) {
+			paths, err = c.SaveFiles("files", dir, func(fh *multipart.FileHeader) string {
+				return "named-" + fh.Filename
+			})
+		})
+
+		b.ServeHTTP(httptest.NewRecorder(), newMultipartRequest("/", map[string][]string{"files": {"a.txt", "../../b.txt"}}))
+		So(err, ShouldBeNil)
+		So(paths, ShouldResemble, []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")})
+		content, _ := ioutil.ReadFile(filepath.Join(dir, "b.txt"))
+		So(string(content), ShouldEqual, "content of ../../b.txt")
+
+		b.ServeHTTP(httptest.NewRecorder(), newMultipartRequest("/named", map[string][]string{"files": {"c.txt"}}))
+		So(err, ShouldBeNil)
+		So(paths, ShouldResemble, []string{filepath.Join(dir, "named-c.txt")})
+
+		b.ServeHTTP(httptest.NewRecorder(), newMultipartRequest("/", map[string][]string{"other": {"c.txt"}}))
+		So(err, ShouldEqual, http.ErrMissingFile)
+	})
+}
@@ -0,0 +1,477 @@
+package baa
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateFunc checks a field value with the rule param,
+// eg: the param of rule min=1 is "1", it is empty for rule without param.
+type ValidateFunc func(v reflect.Value, param string) bool
+
+// FieldError is a field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is a list of field errors,
+// it is treated as 422 Unprocessable Entity by the default error handler.
+type ValidationErrors []*FieldError
+
+// validateRule is a rule in validate tag
+type validateRule struct {
+	name  string
+	param string
+}
+
+// validateField is a field of struct has validate tag
+type validateField struct {
+	index []int
+	name  string
+	rules []validateRule
+	dive  bool           // elements of slice, array or map are validated
+	elem  []validateRule // rules after dive, they are checked on every element
+}
+
+// validateStruct is the parsed validate fields of struct type
+type validateStruct struct {
+	fields []validateField
+	err    error
+}
+
+// validators builtin validation rules
+var validators = map[string]ValidateFunc{
+	"required": validateRequired,
+	"min":      validateMin,
+	"max":      validateMax,
+	"len":      validateLen,
+	"email":    validateEmail,
+	"oneof":    validateOneOf,
+}
+
+// validateParams checks params of builtin rules when fields are parsed
+var validateParams = map[string]func(param string) error{
+	"min": validateNumberParam,
+	"max": validateNumberParam,
+	"len": validateNumberParam,
+	"oneof": func(param string) error {
+		if strings.TrimSpace(param) == "" {
+			return errors.New("options can not be empty")
+		}
+		return nil
+	},
+}
+
+// validateNameTags tags used as field name in errors
+var validateNameTags = []string{"json", "form", "query", "param", "header", "cookie", "xml"}
+
+// Error returns the field error message
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Error returns all of the field errors message
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// RegisterValidation registers a custom validation rule used in validate tag,
+// it overrides the builtin rule with same name.
+func (b *Baa) RegisterValidation(name string, fn ValidateFunc) {
+	if name == "" || fn == nil {
+		panic("baa.RegisterValidation name and func can not be empty")
+	}
+	b.validateMu.Lock()
+	if b.validators == nil {
+		b.validators = make(map[string]ValidateFunc)
+	}
+	b.validators[name] = fn
+	// rules of cached structs are checked again with the new rule
+	b.validateStructs = nil
+	b.validateMu.Unlock()
+}
+
+// validator returns the validation rule by name
+func (b *Baa) validator(name string) ValidateFunc {
+	b.validateMu.RLock()
+	fn := b.validators[name]
+	b.validateMu.RUnlock()
+	if fn != nil {
+		return fn
+	}
+	return validators[name]
+}
+
+// validateFields returns validate fields of struct type, rules of tags are checked when it is parsed
+func (b *Baa) validateFields(t reflect.Type) ([]validateField, error) {
+	b.validateMu.RLock()
+	v := b.validateStructs[t]
+	b.validateMu.RUnlock()
+	if v != nil {
+		return v.fields, v.err
+	}
+	v = new(validateStruct)
+	v.fields, v.err = b.parseValidateFields(t, nil, "", map[reflect.Type]bool{t: true})
+	b.validateMu.Lock()
+	if b.validateStructs == nil {
+		b.validateStructs = make(map[reflect.Type]*validateStruct)
+	}
+	b.validateStructs[t] = v
+	b.validateMu.Unlock()
+	return v.fields, v.err
+}
+
+// Validate checks fields of struct by validate tag, returns ValidationErrors has all failed fields.
+//
+//	validate:"required,min=1,max=100,email,oneof=a b"
+//
+// a zero value skips the other rules with omitempty, nested structs are checked if they have validate tags.
+// Rules after dive are checked on every element of slice, array or map, struct elements are validated too:
+//
+//	validate:"max=10,dive,required"
+//
+// An error is returned for unknown rules and bad params.
+func (c *Context) Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("baa.Validate value can not be nil")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("baa.Validate value must be a struct")
+	}
+
+	var errs ValidationErrors
+	if err := c.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct validates fields of struct rv, prefix is added to the name of failed fields
+func (c *Context) validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := c.baa.validateFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok {
+			continue
+		}
+		name := prefix + f.name
+		if !c.validateRules(fv, name, f.rules, errs) || !f.dive {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		switch fv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < fv.Len(); i++ {
+				if err := c.validateElem(fv.Index(i), name+"["+strconv.Itoa(i)+"]", f.elem, errs); err != nil {
+					return err
+				}
+			}
+		case reflect.Map:
+			keys := fv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+			})
+			for _, k := range keys {
+				if err := c.validateElem(fv.MapIndex(k), name+"["+fmt.Sprint(k.Interface())+"]", f.elem, errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateElem validates an element of dive field by rules, struct element is validated by its tags
+func (c *Context) validateElem(ev reflect.Value, name string, rules []validateRule, errs *ValidationErrors) error {
+	if !c.validateRules(ev, name, rules, errs) {
+		return nil
+	}
+	for ev.Kind() == reflect.Ptr || ev.Kind() == reflect.Interface {
+		if ev.IsNil() {
+			return nil
+		}
+		ev = ev.Elem()
+	}
+	if ev.Kind() != reflect.Struct || ev.Type() == timeType {
+		return nil
+	}
+	return c.validateStruct(ev, name+".", errs)
+}
+
+// validateRules checks v by rules, it returns false when a rule fails or v is skipped by omitempty
+func (c *Context) validateRules(v reflect.Value, name string, rules []validateRule, errs *ValidationErrors) bool {
+	for _, r := range rules {
+		if r.name == "omitempty" {
+			if v.IsZero() {
+				return false
+			}
+			continue
+		}
+		if !c.baa.validator(r.name)(v, r.param) {
+			*errs = append(*errs, &FieldError{Field: name, Rule: r.name, Param: r.param, Message: validateMessage(r)})
+			return false
+		}
+	}
+	return true
+}
+
+// fieldByIndex returns nested field, ok is false when a struct pointer in path is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// parseValidateFields parses validate fields of struct type,
+// visited has the struct types of current path, they are not parsed again.
+func (b *Baa) parseValidateFields(t reflect.Type, index []int, prefix string, visited map[reflect.Type]bool) ([]validateField, error) {
+	var fields []validateField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		f := validateField{name: prefix + validateFieldName(sf)}
+		f.index = make([]int, len(index)+1)
+		copy(f.index, index)
+		f.index[len(index)] = i
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			for _, rule := range strings.Split(tag, ",") {
+				r := validateRule{name: strings.TrimSpace(rule)}
+				if p := strings.IndexByte(r.name, '='); p >= 0 {
+					r.param = r.name[p+1:]
+					r.name = r.name[:p]
+				}
+				if err := b.checkValidateRule(r, f.dive); err != nil {
+					return nil, fmt.Errorf("baa.Validate field [%s] of %s: %s", f.name, t, err)
+				}
+				switch {
+				case r.name == "dive":
+					f.dive = true
+				case f.dive:
+					f.elem = append(f.elem, r)
+				default:
+					f.rules = append(f.rules, r)
+				}
+			}
+			if f.dive {
+				if err := b.checkDive(sf.Type, visited); err != nil {
+					return nil, fmt.Errorf("baa.Validate field [%s] of %s: %s", f.name, t, err)
+				}
+			}
+			fields = append(fields, f)
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || ft == timeType || visited[ft] {
+			continue
+		}
+		nested := f.name + "."
+		if sf.Anonymous {
+			nested = prefix
+		}
+		visited[ft] = true
+		sub, err := b.parseValidateFields(ft, f.index, nested, visited)
+		delete(visited, ft)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, sub...)
+	}
+	return fields, nil
+}
+
+// checkDive checks the dive field is a slice, array or map, and the tags of struct elements,
+// struct types of current path are validated when the element is validated.
+func (b *Baa) checkDive(t reflect.Type, visited map[reflect.Type]bool) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return errors.New("dive on " + t.String() + ", it must be a slice, array or map")
+	}
+	et := t.Elem()
+	if et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct || et == timeType || visited[et] {
+		return nil
+	}
+	visited[et] = true
+	_, err := b.parseValidateFields(et, nil, "", visited)
+	delete(visited, et)
+	return err
+}
+
+// checkValidateRule checks the rule name and the param of builtin rule, dive can be used once
+func (b *Baa) checkValidateRule(r validateRule, dive bool) error {
+	switch r.name {
+	case "":
+		return errors.New("empty rule")
+	case "dive":
+		if dive {
+			return errors.New("dive is used more than once")
+		}
+		return nil
+	case "omitempty":
+		return nil
+	}
+	b.validateMu.RLock()
+	custom := b.validators[r.name] != nil
+	b.validateMu.RUnlock()
+	if custom {
+		return nil
+	}
+	if validators[r.name] == nil {
+		return errors.New("unknown rule [" + r.name + "]")
+	}
+	if check := validateParams[r.name]; check != nil {
+		if err := check(r.param); err != nil {
+			return fmt.Errorf("invalid param [%s] of rule [%s]: %s", r.param, r.name, err)
+		}
+	}
+	return nil
+}
+
+// validateFieldName returns field name used in errors
+func validateFieldName(sf reflect.StructField) string {
+	for _, tag := range validateNameTags {
+		if name := strings.Split(sf.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// validateMessage returns the message of failed rule
+func validateMessage(r validateRule) string {
+	switch r.name {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + r.param
+	case "max":
+		return "must be at most " + r.param
+	case "len":
+		return "length must be " + r.param
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of [" + r.param + "]"
+	}
+	return "is invalid"
+}
+
+func validateRequired(v reflect.Value, param string) bool {
+	return !v.IsZero()
+}
+
+// validateSize returns size of value, it is the value for number, the length for others
+func validateSize(v reflect.Value, param string) (size, limit float64, ok bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, 0, false
+		}
+		v = v.Elem()
+	}
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), limit, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), limit, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), limit, true
+	}
+	return 0, 0, false
+}
+
+// validateNumberParam checks param of rules compare with a number
+func validateNumberParam(param string) error {
+	_, err := strconv.ParseFloat(param, 64)
+	return err
+}
+
+func validateMin(v reflect.Value, param string) bool {
+	size, limit, ok := validateSize(v, param)
+	return ok && size >= limit
+}
+
+func validateMax(v reflect.Value, param string) bool {
+	size, limit, ok := validateSize(v, param)
+	return ok && size <= limit
+}
+
+func validateLen(v reflect.Value, param string) bool {
+	size, limit, ok := validateSize(v, param)
+	return ok && size == limit
+}
+
+func validateEmail(v reflect.Value, param string) bool {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func validateOneOf(v reflect.Value, param string) bool {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	s := fmt.Sprint(v.Interface())
+	for _, option := range strings.Fields(param) {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
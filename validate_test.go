@@ -0,0 +1,162 @@
+package baa
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name    string           `json:"name" validate:"required,min=2,max=10"`
+	Email   string           `json:"email" validate:"omitempty,email"`
+	Age     int              `json:"age" validate:"min=1,max=150"`
+	Role    string           `json:"role" validate:"oneof=admin user"`
+	Tags    []string         `json:"tags" validate:"max=2"`
+	Code    string           `json:"code" validate:"len=4,upper"`
+	Address validateAddress  `json:"address"`
+	Backup  *validateAddress `json:"backup"`
+}
+
+type validateNode struct {
+	Name     string          `json:"name" validate:"required"`
+	Next     *validateNode   `json:"next"`
+	Children []*validateNode `json:"children" validate:"dive"`
+}
+
+func TestContextValidate(t *testing.T) {
+	Convey("context validate", t, func() {
+		b2 := New()
+		b2.RegisterValidation("upper", func(v reflect.Value, param string) bool {
+			return v.String() == strings.ToUpper(v.String())
+		})
+		c2 := NewContext(nil, nil, b2)
+
+		Convey("valid struct", func() {
+			u := validateUser{Name: "baa", Age: 5, Role: "admin", Code: "ABCD", Address: validateAddress{City: "bj"}}
+			So(c2.Validate(&u), ShouldBeNil)
+		})
+
+		Convey("invalid struct", func() {
+			u := validateUser{Name: "b", Email: "baa", Role: "guest", Tags: []string{"a", "b", "c"}, Code: "abcd",
+				Backup: &validateAddress{}}
+			err := c2.Validate(u)
+			So(err, ShouldNotBeNil)
+			var errs ValidationErrors
+			So(errors.As(err, &errs), ShouldBeTrue)
+			fields := make([]string, len(errs))
+			for i := range errs {
+				fields[i] = errs[i].Field + ":" + errs[i].Rule
+			}
+			So(fields, ShouldResemble, []string{"name:min", "email:email", "age:min", "role:oneof",
+				"tags:max", "code:upper", "address.city:required", "backup.city:required"})
+			So(errs[0].Error(), ShouldEqual, "name must be at least 2")
+		})
+
+		Convey("invalid value", func() {
+			So(c2.Validate(1), ShouldNotBeNil)
+			So(c2.Validate((*validateUser)(nil)), ShouldNotBeNil)
+		})
+
+		Convey("unknown rule", func() {
+			err := c2.Validate(struct {
+				Name string `validate:"unknown"`
+			}{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown rule [unknown]")
+		})
+
+		Convey("invalid rule param", func() {
+			err := c2.Validate(struct {
+				Age int `validate:"min=one"`
+			}{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid param [one] of rule [min]")
+		})
+
+		Convey("self referential struct", func() {
+			n := validateNode{Next: &validateNode{}, Children: []*validateNode{{Name: "a"}, {Children: []*validateNode{{}}}}}
+			err := c2.Validate(&n)
+			var errs ValidationErrors
+			So(errors.As(err, &errs), ShouldBeTrue)
+			fields := make([]string, len(errs))
+			for i := range errs {
+				fields[i] = errs[i].Field
+			}
+			So(fields, ShouldResemble, []string{"name", "children[1].name", "children[1].children[0].name"})
+		})
+
+		Convey("dive into elements", func() {
+			v := struct {
+				Tags      []string                    `json:"tags" validate:"max=3,dive,required,min=2"`
+				Addresses []validateAddress           `json:"addresses" validate:"dive"`
+				Scores    map[string]int              `json:"scores" validate:"dive,max=100"`
+				Backups   map[string]*validateAddress `json:"backups" validate:"omitempty,dive,required"`
+			}{
+				Tags:      []string{"ok", "", "a"},
+				Addresses: []validateAddress{{City: "bj"}, {}},
+				Scores:    map[string]int{"b": 101, "a": 100, "c": 200},
+			}
+			err := c2.Validate(&v)
+			var errs ValidationErrors
+			So(errors.As(err, &errs), ShouldBeTrue)
+			fields := make([]string, len(errs))
+			for i := range errs {
+				fields[i] = errs[i].Field + ":" + errs[i].Rule
+			}
+			So(fields, ShouldResemble, []string{"tags[1]:required", "tags[2]:min", "addresses[1].city:required",
+				"scores[b]:max", "scores[c]:max"})
+
+			v.Tags = []string{"a1", "b2", "c3", "d4"}
+			v.Addresses, v.Scores = nil, nil
+			v.Backups = map[string]*validateAddress{"x": nil}
+			errs = nil
+			So(errors.As(c2.Validate(&v), &errs), ShouldBeTrue)
+			So(len(errs), ShouldEqual, 2)
+			So(errs[0].Field+":"+errs[0].Rule, ShouldEqual, "tags:max")
+			So(errs[1].Field+":"+errs[1].Rule, ShouldEqual, "backups[x]:required")
+		})
+
+		Convey("dive on non collection field", func() {
+			err := c2.Validate(struct {
+				Address validateAddress `validate:"dive"`
+			}{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "must be a slice, array or map")
+			So(c2.Validate(struct {
+				Tags []string `validate:"dive,dive"`
+			}{}), ShouldNotBeNil)
+		})
+
+		Convey("bind then validate", func() {
+			b2.Post("/validate", func(c *Context) {
+				var u validateUser
+				if err := c.Bind(&u); err != nil {
+					c.Error(err)
+					return
+				}
+				c.String(200, "ok")
+			})
+			req, _ := http.NewRequest("POST", "/validate", strings.NewReader(`{"name":"b"}`))
+			req.Header.Set("Content-Type", ApplicationJSON)
+			w := httptest.NewRecorder()
+			b2.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			var body struct {
+				Details []*FieldError `json:"details"`
+			}
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(len(body.Details), ShouldBeGreaterThan, 1)
+			So(body.Details[0].Field, ShouldEqual, "name")
+		})
+	})
+}